@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one append-only audit log entry. The subject itself
+// isn't logged, only a hash of it, so the audit trail doesn't become a
+// second copy of message content.
+type AuditRecord struct {
+	Timestamp   time.Time `json:"timestamp"`
+	KeyID       string    `json:"key_id"`
+	Recipient   string    `json:"recipient"`
+	SubjectHash string    `json:"subject_hash"`
+	Result      string    `json:"result"`
+}
+
+// AuditLog appends AuditRecords to a file, one JSON object per line.
+type AuditLog struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenAuditLog opens (creating and appending to) the audit log file at
+// path.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return &AuditLog{f: f}, nil
+}
+
+// Close closes the underlying file.
+func (a *AuditLog) Close() error {
+	return a.f.Close()
+}
+
+// Record appends one audit entry.
+func (a *AuditLog) Record(keyID, recipient, subject, result string) error {
+	rec := AuditRecord{
+		Timestamp:   time.Now(),
+		KeyID:       keyID,
+		Recipient:   recipient,
+		SubjectHash: hashSubject(subject),
+		Result:      result,
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("encode audit record: %w", err)
+	}
+	data = append(data, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.f.Write(data)
+	return err
+}
+
+func hashSubject(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// AddKey generates a new API key for owner and persists it to store. The
+// returned secret is the only time the caller will see the raw bearer
+// token; only its hash is stored.
+func AddKey(store *Store, owner string, allowedFrom []string, perMinute, perDay int, scopes []string) (secret string, key Key, err error) {
+	id, err := newKeyID()
+	if err != nil {
+		return "", Key{}, err
+	}
+
+	secret, hash, err := NewSecret()
+	if err != nil {
+		return "", Key{}, err
+	}
+
+	key = Key{
+		ID:          id,
+		Hash:        hash,
+		Owner:       owner,
+		Scopes:      scopes,
+		AllowedFrom: allowedFrom,
+		PerMinute:   perMinute,
+		PerDay:      perDay,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := store.Put(key); err != nil {
+		return "", Key{}, err
+	}
+
+	return secret, key, nil
+}
+
+// RevokeKey marks a key as revoked so Middleware will reject it.
+func RevokeKey(store *Store, id string) error {
+	key, found, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("key %s not found", id)
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	return store.Put(key)
+}
+
+// ListKeys returns every key known to store.
+func ListKeys(store *Store) ([]Key, error) {
+	return store.All()
+}
+
+func newKeyID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "key_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,86 @@
+// Package auth guards the HTTP API with bearer API keys: it validates
+// the Authorization header, enforces per-key send quotas, and provides
+// an append-only audit log (AuditLog) that callers write send attempts to.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Scope names understood by Middleware.
+const (
+	ScopeSend           = "send"
+	ScopeMessagesRead   = "messages:read"
+	ScopeTemplatesAdmin = "templates:admin"
+)
+
+// Key is an API key's metadata. The key's secret is never stored; only
+// its SHA-256 hash (Key.Hash) is, so a leaked database doesn't leak
+// usable credentials.
+type Key struct {
+	ID     string   `json:"id"`
+	Hash   string   `json:"hash"`
+	Owner  string   `json:"owner"`
+	Scopes []string `json:"scopes"`
+
+	// AllowedFrom restricts which From addresses this key may send as.
+	// Empty means no restriction.
+	AllowedFrom []string `json:"allowed_from,omitempty"`
+
+	PerMinute int `json:"per_minute"`
+	PerDay    int `json:"per_day"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Revoked reports whether the key has been revoked.
+func (k Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// HasScope reports whether the key is allowed to perform scope.
+func (k Key) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsFrom reports whether the key may send as from. An empty
+// AllowedFrom list allows any address.
+func (k Key) AllowsFrom(from string) bool {
+	if len(k.AllowedFrom) == 0 {
+		return true
+	}
+	for _, addr := range k.AllowedFrom {
+		if addr == from {
+			return true
+		}
+	}
+	return false
+}
+
+// NewSecret generates a random API key secret and returns both the
+// secret (shown to the caller exactly once) and its hash (what gets
+// persisted).
+func NewSecret() (secret, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("generate key secret: %w", err)
+	}
+	secret = "sk_" + hex.EncodeToString(b)
+	return secret, HashSecret(secret), nil
+}
+
+// HashSecret hashes a bearer token for lookup/comparison.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
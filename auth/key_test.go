@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewSecretHashesMatch(t *testing.T) {
+	secret, hash, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret: %v", err)
+	}
+	if secret == "" || hash == "" {
+		t.Fatalf("NewSecret returned empty secret or hash")
+	}
+	if got := HashSecret(secret); got != hash {
+		t.Errorf("HashSecret(secret) = %q, want %q", got, hash)
+	}
+}
+
+func TestNewSecretUnique(t *testing.T) {
+	secret1, hash1, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret: %v", err)
+	}
+	secret2, hash2, err := NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret: %v", err)
+	}
+	if secret1 == secret2 || hash1 == hash2 {
+		t.Errorf("NewSecret produced duplicate values across calls")
+	}
+}
+
+func TestKeyRevoked(t *testing.T) {
+	k := Key{}
+	if k.Revoked() {
+		t.Errorf("zero-value Key reported Revoked() = true")
+	}
+
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	k.RevokedAt = &now
+	if !k.Revoked() {
+		t.Errorf("Key with RevokedAt set reported Revoked() = false")
+	}
+}
+
+func TestKeyHasScope(t *testing.T) {
+	k := Key{Scopes: []string{ScopeSend}}
+	if !k.HasScope(ScopeSend) {
+		t.Errorf("HasScope(%q) = false, want true", ScopeSend)
+	}
+	if k.HasScope("admin") {
+		t.Errorf("HasScope(%q) = true, want false", "admin")
+	}
+}
+
+func TestKeyAllowsFrom(t *testing.T) {
+	unrestricted := Key{}
+	if !unrestricted.AllowsFrom("anyone@example.com") {
+		t.Errorf("AllowsFrom with empty AllowedFrom should allow any address")
+	}
+
+	restricted := Key{AllowedFrom: []string{"allowed@example.com"}}
+	if !restricted.AllowsFrom("allowed@example.com") {
+		t.Errorf("AllowsFrom(%q) = false, want true", "allowed@example.com")
+	}
+	if restricted.AllowsFrom("other@example.com") {
+		t.Errorf("AllowsFrom(%q) = true, want false", "other@example.com")
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type ctxKey struct{}
+
+// FromContext returns the API key that authenticated the current
+// request, if any.
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(ctxKey{}).(Key)
+	return key, ok
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Middleware validates the bearer API key on every request, enforces
+// its per-minute/per-day quota, and requires it carry scope. On success
+// it attaches the Key to the request context (retrieve with
+// FromContext) before calling next.
+func Middleware(store *Store, scope string) func(http.Handler) http.Handler {
+	q := newQuotas()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if token == "" || token == r.Header.Get("Authorization") {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "missing bearer token"})
+				return
+			}
+
+			key, found, err := store.FindByHash(HashSecret(token))
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to validate api key"})
+				return
+			}
+			if !found || key.Revoked() {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid api key"})
+				return
+			}
+			if !key.HasScope(scope) {
+				writeJSON(w, http.StatusForbidden, map[string]string{"error": "api key lacks required scope"})
+				return
+			}
+			if !q.Allow(key) {
+				writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ctxKey{}, key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
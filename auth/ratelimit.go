@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// quotas hands out per-key minute and day token buckets, creating them
+// on first use from that key's configured PerMinute/PerDay limits.
+type quotas struct {
+	mu     sync.Mutex
+	minute map[string]*rate.Limiter
+	day    map[string]*rate.Limiter
+}
+
+func newQuotas() *quotas {
+	return &quotas{
+		minute: make(map[string]*rate.Limiter),
+		day:    make(map[string]*rate.Limiter),
+	}
+}
+
+// Allow reports whether key is currently within both its per-minute and
+// per-day quota, consuming one token from each bucket if so. A zero
+// limit means unlimited.
+func (q *quotas) Allow(key Key) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if key.PerMinute > 0 {
+		l, ok := q.minute[key.ID]
+		if !ok {
+			l = rate.NewLimiter(rate.Limit(float64(key.PerMinute)/60.0), key.PerMinute)
+			q.minute[key.ID] = l
+		}
+		if !l.Allow() {
+			return false
+		}
+	}
+
+	if key.PerDay > 0 {
+		l, ok := q.day[key.ID]
+		if !ok {
+			l = rate.NewLimiter(rate.Limit(float64(key.PerDay)/86400.0), key.PerDay)
+			q.day[key.ID] = l
+		}
+		if !l.Allow() {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,45 @@
+package auth
+
+import "testing"
+
+func TestQuotasAllowUnlimitedByDefault(t *testing.T) {
+	q := newQuotas()
+	k := Key{ID: "k1"}
+
+	for i := 0; i < 1000; i++ {
+		if !q.Allow(k) {
+			t.Fatalf("Allow with zero PerMinute/PerDay should never deny, denied on call %d", i)
+		}
+	}
+}
+
+func TestQuotasEnforcePerMinute(t *testing.T) {
+	q := newQuotas()
+	k := Key{ID: "k1", PerMinute: 2}
+
+	if !q.Allow(k) {
+		t.Fatalf("first call within PerMinute quota was denied")
+	}
+	if !q.Allow(k) {
+		t.Fatalf("second call within PerMinute quota was denied")
+	}
+	if q.Allow(k) {
+		t.Fatalf("call exceeding PerMinute quota should have been denied")
+	}
+}
+
+func TestQuotasPerKeyIsolated(t *testing.T) {
+	q := newQuotas()
+	k1 := Key{ID: "k1", PerMinute: 1}
+	k2 := Key{ID: "k2", PerMinute: 1}
+
+	if !q.Allow(k1) {
+		t.Fatalf("k1's first call should be allowed")
+	}
+	if q.Allow(k1) {
+		t.Fatalf("k1's second call should be denied")
+	}
+	if !q.Allow(k2) {
+		t.Fatalf("k2 should have its own quota independent of k1")
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var keysBucket = []byte("keys")
+
+// Store persists API keys, indexed by ID.
+type Store struct {
+	db *bolt.DB
+}
+
+// OpenStore opens (creating if necessary) a BoltDB file at path and
+// ensures the keys bucket exists.
+func OpenStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open bolt db: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(keysBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create keys bucket: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying BoltDB file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Put creates or updates a key.
+func (s *Store) Put(key Key) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("encode key: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(keysBucket).Put([]byte(key.ID), data)
+	})
+}
+
+// Get looks up a key by ID.
+func (s *Store) Get(id string) (Key, bool, error) {
+	var key Key
+	var found bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(keysBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &key)
+	})
+
+	return key, found, err
+}
+
+// FindByHash looks up the key whose secret hashes to hash. It's a linear
+// scan, which is fine for the small number of keys this service expects
+// to manage; an index can be added if that stops being true.
+func (s *Store) FindByHash(hash string) (Key, bool, error) {
+	var found Key
+	var ok bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(keysBucket).ForEach(func(_, data []byte) error {
+			var key Key
+			if err := json.Unmarshal(data, &key); err != nil {
+				return err
+			}
+			if key.Hash == hash {
+				found = key
+				ok = true
+			}
+			return nil
+		})
+	})
+
+	return found, ok, err
+}
+
+// All returns every key.
+func (s *Store) All() ([]Key, error) {
+	var keys []Key
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(keysBucket).ForEach(func(_, data []byte) error {
+			var key Key
+			if err := json.Unmarshal(data, &key); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+
+	return keys, err
+}
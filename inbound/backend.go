@@ -0,0 +1,171 @@
+package inbound
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-smtp"
+)
+
+var (
+	errRecipientDomain = errors.New("recipient domain not accepted")
+	errRecipientDenied = errors.New("recipient not accepted")
+)
+
+// Backend adapts a Config and Receiver into a go-smtp backend.
+type Backend struct {
+	cfg      Config
+	receiver Receiver
+}
+
+// NewBackend builds a go-smtp backend that parses accepted messages and
+// hands them to receiver.
+func NewBackend(cfg Config, receiver Receiver) *Backend {
+	return &Backend{cfg: cfg, receiver: receiver}
+}
+
+// NewSession implements smtp.Backend.
+func (b *Backend) NewSession(c *smtp.Conn) (smtp.Session, error) {
+	return &session{cfg: b.cfg, receiver: b.receiver}, nil
+}
+
+// Server builds a *smtp.Server wired to b, applying the timeouts, size
+// limit, and optional TLS configured on cfg.
+func Server(cfg Config, receiver Receiver) (*smtp.Server, error) {
+	s := smtp.NewServer(NewBackend(cfg, receiver))
+	s.Addr = cfg.Addr
+	s.Domain = cfg.AllowedDomain
+	s.ReadTimeout = cfg.ReadTimeout
+	s.WriteTimeout = cfg.WriteTimeout
+	s.MaxMessageBytes = cfg.MaxMessageSize
+	s.MaxRecipients = 50
+	s.AllowInsecureAuth = true
+
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tls cert: %w", err)
+		}
+		s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return s, nil
+}
+
+type session struct {
+	cfg      Config
+	receiver Receiver
+
+	from string
+	to   []string
+}
+
+func (s *session) AuthPlain(username, password string) error {
+	return nil
+}
+
+func (s *session) Mail(from string, opts *smtp.MailOptions) error {
+	s.from = from
+	return nil
+}
+
+func (s *session) Rcpt(to string, opts *smtp.RcptOptions) error {
+	if s.cfg.AllowedDomain != "" {
+		at := strings.LastIndex(to, "@")
+		if at < 0 || !strings.EqualFold(to[at+1:], s.cfg.AllowedDomain) {
+			return errRecipientDomain
+		}
+	}
+	if s.cfg.RecipientAllow != nil && !s.cfg.RecipientAllow.MatchString(to) {
+		return errRecipientDenied
+	}
+	s.to = append(s.to, to)
+	return nil
+}
+
+func (s *session) Data(r io.Reader) error {
+	msg, err := parseMessage(r)
+	if err != nil {
+		return fmt.Errorf("parse message: %w", err)
+	}
+	msg.From = s.from
+	msg.To = s.to
+	msg.ReceivedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return s.receiver.Receive(ctx, msg)
+}
+
+func (s *session) Reset() {
+	s.from = ""
+	s.to = nil
+}
+
+func (s *session) Logout() error {
+	return nil
+}
+
+// parseMessage decodes an RFC 5322 message, including multipart bodies,
+// into a Message. Only the From/To supplied via the SMTP envelope are
+// trusted; header values supply the Subject and body selection and are
+// kept verbatim in Headers, but never set From or To.
+func parseMessage(r io.Reader) (Message, error) {
+	m, err := mail.ReadMessage(r)
+	if err != nil {
+		return Message{}, err
+	}
+
+	headers := map[string][]string{}
+	for k, v := range m.Header {
+		headers[k] = v
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		subject = m.Header.Get("Subject")
+	}
+
+	msg := Message{
+		Subject: subject,
+		Headers: headers,
+	}
+
+	contentType := m.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		// No (valid) Content-Type header: treat the whole body as plain text.
+		body, readErr := io.ReadAll(m.Body)
+		if readErr != nil {
+			return Message{}, readErr
+		}
+		msg.TextBody = string(body)
+		return msg, nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if err := collectParts(&msg, m.Body, params["boundary"]); err != nil {
+			return Message{}, err
+		}
+		return msg, nil
+	}
+
+	body, err := io.ReadAll(m.Body)
+	if err != nil {
+		return Message{}, err
+	}
+	if mediaType == "text/html" {
+		msg.HTMLBody = string(body)
+	} else {
+		msg.TextBody = string(body)
+	}
+	return msg, nil
+}
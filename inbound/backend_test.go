@@ -0,0 +1,87 @@
+package inbound
+
+import (
+	"strings"
+	"testing"
+)
+
+func buildMultipartMessage(boundary, text, html string) string {
+	var b strings.Builder
+	b.WriteString("Subject: hello\r\n")
+	b.WriteString("Content-Type: multipart/alternative; boundary=" + boundary + "\r\n")
+	b.WriteString("\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/plain\r\n\r\n")
+	b.WriteString(text + "\r\n")
+	b.WriteString("--" + boundary + "\r\n")
+	b.WriteString("Content-Type: text/html\r\n\r\n")
+	b.WriteString(html + "\r\n")
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.String()
+}
+
+func TestParseMessagePlainText(t *testing.T) {
+	raw := "Subject: hello\r\nContent-Type: text/plain\r\n\r\nplain body"
+
+	msg, err := parseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parseMessage: %v", err)
+	}
+	if msg.Subject != "hello" {
+		t.Errorf("Subject = %q, want %q", msg.Subject, "hello")
+	}
+	if msg.TextBody != "plain body" {
+		t.Errorf("TextBody = %q, want %q", msg.TextBody, "plain body")
+	}
+}
+
+func TestParseMessageNoContentType(t *testing.T) {
+	raw := "Subject: hello\r\n\r\nplain body, no content-type header"
+
+	msg, err := parseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parseMessage: %v", err)
+	}
+	if msg.TextBody != "plain body, no content-type header" {
+		t.Errorf("TextBody = %q, want the whole body treated as plain text", msg.TextBody)
+	}
+}
+
+func TestParseMessageMultipartAlternative(t *testing.T) {
+	raw := buildMultipartMessage("BOUNDARY", "plain body", "<p>html body</p>")
+
+	msg, err := parseMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parseMessage: %v", err)
+	}
+	if msg.TextBody != "plain body" {
+		t.Errorf("TextBody = %q, want %q", msg.TextBody, "plain body")
+	}
+	if msg.HTMLBody != "<p>html body</p>" {
+		t.Errorf("HTMLBody = %q, want %q", msg.HTMLBody, "<p>html body</p>")
+	}
+}
+
+func TestParseMessageNestedMultipart(t *testing.T) {
+	inner := buildMultipartMessage("INNER", "nested plain", "<p>nested html</p>")
+	_, innerBody, _ := strings.Cut(inner, "\r\n\r\n")
+
+	var b strings.Builder
+	b.WriteString("Subject: hello\r\n")
+	b.WriteString("Content-Type: multipart/mixed; boundary=OUTER\r\n\r\n")
+	b.WriteString("--OUTER\r\n")
+	b.WriteString("Content-Type: multipart/alternative; boundary=INNER\r\n\r\n")
+	b.WriteString(innerBody)
+	b.WriteString("--OUTER--\r\n")
+
+	msg, err := parseMessage(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("parseMessage: %v", err)
+	}
+	if msg.TextBody != "nested plain" {
+		t.Errorf("TextBody = %q, want %q", msg.TextBody, "nested plain")
+	}
+	if msg.HTMLBody != "<p>nested html</p>" {
+		t.Errorf("HTMLBody = %q, want %q", msg.HTMLBody, "<p>nested html</p>")
+	}
+}
@@ -0,0 +1,64 @@
+// Package inbound implements the mail-receiving side of the service: an
+// SMTP server that accepts MAIL FROM/RCPT TO/DATA from the outside world,
+// parses the resulting RFC 5322 message, and hands it off to a Receiver.
+package inbound
+
+import (
+	"context"
+	"regexp"
+	"time"
+)
+
+// Message is a parsed inbound email, independent of how it arrived (real
+// SMTP session or the HTTP testing shim).
+type Message struct {
+	From       string
+	To         []string
+	Subject    string
+	TextBody   string
+	HTMLBody   string
+	Headers    map[string][]string
+	ReceivedAt time.Time
+}
+
+// Receiver is notified of every inbound message once it has been fully
+// parsed. Implementations decide how (or whether) to persist it.
+type Receiver interface {
+	Receive(ctx context.Context, msg Message) error
+}
+
+// Config controls the inbound SMTP server.
+type Config struct {
+	// Addr is the listen address, e.g. ":2525".
+	Addr string
+
+	// AllowedDomain restricts RCPT TO to a single recipient domain. Empty
+	// means any domain is accepted.
+	AllowedDomain string
+
+	// RecipientAllow, if set, further restricts RCPT TO to addresses
+	// matching this pattern.
+	RecipientAllow *regexp.Regexp
+
+	// MaxMessageSize caps the size of an incoming DATA payload in bytes.
+	MaxMessageSize int64
+
+	// TLSCertFile and TLSKeyFile, if both set, enable STARTTLS.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// ReadTimeout and WriteTimeout bound a single SMTP command.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// DefaultConfig returns a Config with conservative defaults; callers
+// typically override Addr and AllowedDomain.
+func DefaultConfig() Config {
+	return Config{
+		Addr:           ":2525",
+		MaxMessageSize: 25 << 20, // 25 MiB
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   30 * time.Second,
+	}
+}
@@ -0,0 +1,53 @@
+package inbound
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+)
+
+// collectParts walks a multipart body (recursing into nested multiparts,
+// e.g. multipart/alternative inside multipart/mixed) and fills in msg's
+// TextBody/HTMLBody from the first text/plain and text/html parts found.
+func collectParts(msg *Message, body io.Reader, boundary string) error {
+	if boundary == "" {
+		return nil
+	}
+
+	mr := multipart.NewReader(body, boundary)
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		contentType := part.Header.Get("Content-Type")
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			mediaType = "text/plain"
+		}
+
+		if strings.HasPrefix(mediaType, "multipart/") {
+			if err := collectParts(msg, part, params["boundary"]); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case mediaType == "text/html" && msg.HTMLBody == "":
+			msg.HTMLBody = string(data)
+		case mediaType == "text/plain" && msg.TextBody == "":
+			msg.TextBody = string(data)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package inbound
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var messagesBucket = []byte("messages")
+
+// maxStoredMessages caps how many received messages Store retains; once
+// full, the oldest message is dropped to make room for the new one,
+// bounding memory/disk use on a long-running inbound listener.
+const maxStoredMessages = 10000
+
+// Store persists received messages durably in a BoltDB file, so they
+// survive a restart, and serves them back out (oldest first) for the
+// GET /receive endpoint. It implements Receiver.
+type Store struct {
+	db *bolt.DB
+}
+
+// NewStore opens (creating if necessary) a BoltDB file at path and
+// ensures the messages bucket exists.
+func NewStore(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open bolt db: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(messagesBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create messages bucket: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying BoltDB file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Receive implements Receiver by persisting msg keyed by a monotonically
+// increasing sequence number, so List returns messages oldest first.
+// Once the store holds maxStoredMessages, the oldest message is dropped
+// to bound memory/disk use.
+func (s *Store) Receive(ctx context.Context, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(messagesBucket)
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("next sequence: %w", err)
+		}
+		if err := b.Put(sequenceKey(seq), data); err != nil {
+			return fmt.Errorf("put message: %w", err)
+		}
+
+		if b.Stats().KeyN > maxStoredMessages {
+			if oldest, _ := b.Cursor().First(); oldest != nil {
+				if err := b.Delete(oldest); err != nil {
+					return fmt.Errorf("evict oldest message: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// List returns every message received so far, oldest first.
+func (s *Store) List() []Message {
+	var messages []Message
+
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(messagesBucket).ForEach(func(_, data []byte) error {
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err != nil {
+				return err
+			}
+			messages = append(messages, msg)
+			return nil
+		})
+	})
+
+	return messages
+}
+
+// sequenceKey encodes seq as a big-endian byte string so BoltDB's
+// lexicographic key order matches insertion order.
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
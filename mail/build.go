@@ -0,0 +1,211 @@
+package mail
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Build renders m into a full RFC 5322 message, ready to hand to an SMTP
+// DATA command. from is the envelope/header sender address; id and now
+// are used for the Message-ID and Date headers respectively (callers
+// pass them in rather than Build calling time.Now() itself, so tests can
+// be deterministic).
+func (m Message) Build(from, id string, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", strings.Join(m.To, ", "))
+	if len(m.Cc) > 0 {
+		header.Set("Cc", strings.Join(m.Cc, ", "))
+	}
+	if m.ReplyTo != "" {
+		header.Set("Reply-To", m.ReplyTo)
+	}
+	header.Set("Subject", encodeHeader(m.Subject))
+	header.Set("Message-ID", fmt.Sprintf("<%s@%s>", id, hostFromAddress(from)))
+	header.Set("Date", now.Format(time.RFC1123Z))
+	header.Set("MIME-Version", "1.0")
+
+	// Caller-supplied headers are only applied to keys not already set
+	// above, so they can't be used to override Subject/From/etc, but
+	// they're still free to add anything else.
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if header.Get(k) == "" {
+			header.Set(k, m.Headers[k])
+		}
+	}
+
+	hasAttachments := len(m.Attachments) > 0
+	hasHTML := m.HTMLBody != ""
+
+	switch {
+	case !hasAttachments && !hasHTML:
+		header.Set("Content-Type", `text/plain; charset="utf-8"`)
+		writeHeader(&buf, header)
+		buf.WriteString("\r\n")
+		buf.WriteString(m.Body)
+		buf.WriteString("\r\n")
+		return buf.Bytes(), nil
+
+	case !hasAttachments && hasHTML:
+		mw := multipart.NewWriter(&buf)
+		header.Set("Content-Type", fmt.Sprintf(`multipart/alternative; boundary="%s"`, mw.Boundary()))
+		writeHeader(&buf, header)
+		if err := writeAlternative(mw, m.Body, m.HTMLBody); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	default:
+		mw := multipart.NewWriter(&buf)
+		header.Set("Content-Type", fmt.Sprintf(`multipart/mixed; boundary="%s"`, mw.Boundary()))
+		writeHeader(&buf, header)
+
+		if hasHTML {
+			var altBuf bytes.Buffer
+			altw := multipart.NewWriter(&altBuf)
+			altPart, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type": {fmt.Sprintf(`multipart/alternative; boundary="%s"`, altw.Boundary())},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := writeAlternative(altw, m.Body, m.HTMLBody); err != nil {
+				return nil, err
+			}
+			if _, err := altPart.Write(altBuf.Bytes()); err != nil {
+				return nil, err
+			}
+		} else {
+			part, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type": {`text/plain; charset="utf-8"`},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if _, err := part.Write([]byte(m.Body)); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, a := range m.Attachments {
+			if err := writeAttachment(mw, a); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+func writeAlternative(mw *multipart.Writer, text, html string) error {
+	if err := writeAlternativeBody(mw, text, html); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+func writeAlternativeBody(mw *multipart.Writer, text, html string) error {
+	textPart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {`text/plain; charset="utf-8"`},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := textPart.Write([]byte(text)); err != nil {
+		return err
+	}
+
+	htmlPart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {`text/html; charset="utf-8"`},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := htmlPart.Write([]byte(html)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeAttachment(mw *multipart.Writer, a Attachment) error {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf(`attachment; filename="%s"`, a.Filename)},
+	})
+	if err != nil {
+		return err
+	}
+
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(a.Data)))
+	base64.StdEncoding.Encode(encoded, a.Data)
+	_, err = part.Write(encoded)
+	return err
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		buf.WriteString(k)
+		buf.WriteString(": ")
+		buf.WriteString(header.Get(k))
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("\r\n")
+}
+
+// encodeHeader Q-encodes subject if it contains non-ASCII bytes, leaving
+// plain ASCII subjects untouched.
+func encodeHeader(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] > 127 {
+			return mime.QEncoding.Encode("utf-8", s)
+		}
+	}
+	return s
+}
+
+func hostFromAddress(addr string) string {
+	if at := strings.LastIndex(addr, "@"); at >= 0 {
+		return addr[at+1:]
+	}
+	return "localhost"
+}
+
+// NewMessageID generates a random, RFC 5322-friendly Message-ID local
+// part.
+func NewMessageID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,124 @@
+package mail
+
+import (
+	"bytes"
+	"io"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+var buildNow = time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+func parseBuilt(t *testing.T, data []byte) (textproto.MIMEHeader, []byte) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("parse built message: %v", err)
+	}
+	body, err := io.ReadAll(msg.Body)
+	if err != nil {
+		t.Fatalf("read built body: %v", err)
+	}
+	return textproto.MIMEHeader(msg.Header), body
+}
+
+func TestBuildPlainText(t *testing.T) {
+	m := Message{
+		To:      []string{"to@example.com"},
+		Subject: "hello",
+		Body:    "plain body",
+	}
+
+	data, err := m.Build("from@example.com", "msg1", buildNow)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	header, body := parseBuilt(t, data)
+	if got := header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain prefix", got)
+	}
+	if got := header.Get("Subject"); got != "hello" {
+		t.Errorf("Subject = %q, want %q", got, "hello")
+	}
+	if !strings.Contains(string(body), "plain body") {
+		t.Errorf("body = %q, want it to contain %q", body, "plain body")
+	}
+}
+
+func TestBuildAlternativeHTML(t *testing.T) {
+	m := Message{
+		To:       []string{"to@example.com"},
+		Subject:  "hello",
+		Body:     "plain body",
+		HTMLBody: "<p>html body</p>",
+	}
+
+	data, err := m.Build("from@example.com", "msg2", buildNow)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	header, _ := parseBuilt(t, data)
+	if got := header.Get("Content-Type"); !strings.HasPrefix(got, "multipart/alternative") {
+		t.Errorf("Content-Type = %q, want multipart/alternative prefix", got)
+	}
+
+	full := string(data)
+	if !strings.Contains(full, "plain body") || !strings.Contains(full, "<p>html body</p>") {
+		t.Errorf("built message missing text or html part:\n%s", full)
+	}
+}
+
+func TestBuildMixedWithAttachment(t *testing.T) {
+	m := Message{
+		To:      []string{"to@example.com"},
+		Subject: "hello",
+		Body:    "plain body",
+		Attachments: []Attachment{
+			{Filename: "a.txt", ContentType: "text/plain", Data: []byte("attachment data")},
+		},
+	}
+
+	data, err := m.Build("from@example.com", "msg3", buildNow)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	header, _ := parseBuilt(t, data)
+	if got := header.Get("Content-Type"); !strings.HasPrefix(got, "multipart/mixed") {
+		t.Errorf("Content-Type = %q, want multipart/mixed prefix", got)
+	}
+	if !strings.Contains(string(data), `filename="a.txt"`) {
+		t.Errorf("built message missing attachment disposition:\n%s", data)
+	}
+}
+
+func TestBuildCallerHeadersCannotOverrideCore(t *testing.T) {
+	m := Message{
+		To:      []string{"to@example.com"},
+		Subject: "hello",
+		Body:    "plain body",
+		Headers: map[string]string{
+			"Subject": "smuggled",
+			"X-Extra": "yes",
+		},
+	}
+
+	data, err := m.Build("from@example.com", "msg4", buildNow)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	header, _ := parseBuilt(t, data)
+	if got := header.Get("Subject"); got != "hello" {
+		t.Errorf("Subject = %q, want caller Headers unable to override it, still %q", got, "hello")
+	}
+	if got := header.Get("X-Extra"); got != "yes" {
+		t.Errorf("X-Extra = %q, want %q", got, "yes")
+	}
+}
@@ -0,0 +1,39 @@
+// Package mail holds the Message type shared between the HTTP handlers,
+// the outbound transports, and the queue: it is the common
+// currency everything in this service passes around.
+package mail
+
+// Attachment is a file attached to a Message, sent as part of a
+// multipart/mixed body.
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Message is an outbound email. Body is the plain-text part and HTMLBody
+// an optional HTML part; when HTMLBody is present, both are sent as
+// multipart/alternative parts, even if Body is empty.
+type Message struct {
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Bcc     []string `json:"bcc,omitempty"`
+	ReplyTo string   `json:"reply_to,omitempty"`
+
+	Subject  string `json:"subject"`
+	Body     string `json:"body"`
+	HTMLBody string `json:"html_body,omitempty"`
+
+	Attachments []Attachment      `json:"attachments,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// Recipients returns every envelope recipient (To, Cc, and Bcc
+// combined), the set a transport should issue RCPT TO for.
+func (m Message) Recipients() []string {
+	recipients := make([]string, 0, len(m.To)+len(m.Cc)+len(m.Bcc))
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	recipients = append(recipients, m.Bcc...)
+	return recipients
+}
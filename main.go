@@ -2,145 +2,239 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/smtp"
 	"os"
+	"os/signal"
 	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/rs/cors"
+
+	"github.com/shokr12/Email_sender/auth"
+	"github.com/shokr12/Email_sender/inbound"
+	"github.com/shokr12/Email_sender/mail"
+	"github.com/shokr12/Email_sender/metrics"
+	"github.com/shokr12/Email_sender/queue"
+	"github.com/shokr12/Email_sender/templates"
+	"github.com/shokr12/Email_sender/transport"
 )
 
-type Message struct {
-	Email   string `json:"email"`
-	Subject string `json:"subject"`
-	Body    string `json:"body"`
+// Message is an outbound email as accepted by /send.
+type Message = mail.Message
+
+// maxMessageSize caps the total size of a message's text, HTML, and
+// attachment payloads, replacing the old hard 1000-byte body limit now
+// that a message can carry much more than a plain-text body.
+const maxMessageSize = 10 << 20 // 10 MiB
+
+var emailPattern = regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
+
+// containsCRLF reports whether s contains a carriage return or line
+// feed, which would let it smuggle an extra header line into a
+// message built by mail.Message.Build.
+func containsCRLF(s string) bool {
+	return strings.ContainsAny(s, "\r\n")
 }
 
-func sendGmail(ctx context.Context, host string, port int, username, password, from, to, subject, body string) error {
-	addr := fmt.Sprintf("%s:%d", host, port)
-	d := net.Dialer{}
-	conn, err := d.DialContext(ctx, "tcp", addr)
-	if err != nil {
-		return fmt.Errorf("dial smtp: %w", err)
+func validateMessage(message Message) error {
+	if len(message.To) == 0 || message.Subject == "" || (message.Body == "" && message.HTMLBody == "") {
+		return errors.New("invalid message: at least one recipient, a subject, and a body are required")
 	}
 
-	c, err := smtp.NewClient(conn, host)
-	if err != nil {
-		return fmt.Errorf("new smtp client: %w", err)
+	for _, addr := range message.Recipients() {
+		if !emailPattern.MatchString(addr) {
+			return fmt.Errorf("invalid email format: %s", addr)
+		}
 	}
-	defer c.Close()
 
-	tlsCfg := &tls.Config{ServerName: host}
-	if err := c.StartTLS(tlsCfg); err != nil {
-		return fmt.Errorf("starttls: %w", err)
+	if message.ReplyTo != "" && !emailPattern.MatchString(message.ReplyTo) {
+		return errors.New("invalid reply-to email format")
 	}
 
-	auth := smtp.PlainAuth("", username, password, host)
-	if err := c.Auth(auth); err != nil {
-		return fmt.Errorf("auth: %w", err)
+	if len(message.Subject) > 25 {
+		return errors.New("invalid message: subject is too long")
 	}
 
-	if err := c.Mail(from); err != nil {
-		return fmt.Errorf("mail from: %w", err)
+	// Subject, ReplyTo, and header values end up as raw bytes on a
+	// header line in mail.Message.Build; a stray CR or LF would let a
+	// caller splice in an extra header (e.g. a second Bcc) or smuggle
+	// SMTP commands, so reject them here rather than trusting the
+	// regex-checked address fields to be the only injection point.
+	if containsCRLF(message.Subject) {
+		return errors.New("invalid message: subject must not contain CR or LF")
 	}
-
-	if err := c.Rcpt(to); err != nil {
-		return fmt.Errorf("rcpt to: %w", err)
+	if containsCRLF(message.ReplyTo) {
+		return errors.New("invalid message: reply-to must not contain CR or LF")
+	}
+	for k, v := range message.Headers {
+		if containsCRLF(k) || containsCRLF(v) {
+			return fmt.Errorf("invalid message: header %q must not contain CR or LF", k)
+		}
 	}
 
-	w, err := c.Data()
-	if err != nil {
-		return fmt.Errorf("data: %w", err)
+	size := len(message.Body) + len(message.HTMLBody)
+	for _, a := range message.Attachments {
+		// Filename and ContentType also end up verbatim on a
+		// Content-Disposition/Content-Type header line for this part.
+		if containsCRLF(a.Filename) {
+			return fmt.Errorf("invalid message: attachment filename %q must not contain CR or LF", a.Filename)
+		}
+		if containsCRLF(a.ContentType) {
+			return fmt.Errorf("invalid message: attachment content type %q must not contain CR or LF", a.ContentType)
+		}
+		size += len(a.Data)
+	}
+	if size > maxMessageSize {
+		return errors.New("invalid message: total message size is too large")
 	}
 
-	msg := []byte(
-		"From: " + from + "\r\n" +
-			"To: " + to + "\r\n" +
-			"Subject: " + subject + "\r\n" +
-			"MIME-Version: 1.0\r\n" +
-			"Content-Type: text/plain; charset=\"utf-8\"\r\n" +
-			"\r\n" +
-			body + "\r\n",
-	)
+	return nil
+}
 
-	if _, err := w.Write(msg); err != nil {
-		_ = w.Close()
-		return fmt.Errorf("write: %w", err)
-	}
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
 
-	if err := w.Close(); err != nil {
-		return fmt.Errorf("close data: %w", err)
+type logCtxKey struct{}
+
+// loggerFromContext returns the request-scoped logger attached by
+// withRequestLogging, or the default logger if none is attached (e.g.
+// outside an HTTP request).
+func loggerFromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(logCtxKey{}).(*slog.Logger); ok {
+		return l
 	}
+	return slog.Default()
+}
 
-	return c.Quit()
+// withRequestLogging attaches a logger carrying request_id and
+// remote_addr to the request context, retrievable with
+// loggerFromContext. Handlers that know the recipient add
+// recipient_domain themselves.
+func withRequestLogging(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := newRequestID()
+		if err != nil {
+			id = "unknown"
+		}
+
+		logger := slog.Default().With("request_id", id, "remote_addr", r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), logCtxKey{}, logger)))
+	})
 }
 
-func validateMessage(message Message) error {
-	if message.Email == "" || message.Subject == "" || message.Body == "" {
-		return errors.New("invalid message: email, subject, and body are required")
+func newRequestID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(b), nil
+}
 
-	matched, _ := regexp.MatchString(
-		`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`,
-		message.Email,
-	)
-	if !matched {
-		return errors.New("invalid email format")
+// domainOf returns the part of addr after "@", or "" if addr has none.
+func domainOf(addr string) string {
+	if i := strings.LastIndexByte(addr, '@'); i >= 0 {
+		return addr[i+1:]
 	}
+	return ""
+}
 
-	if len(message.Subject) > 25 {
-		return errors.New("invalid message: subject is too long")
-	}
+// SendMessageHandler validates the incoming Message and enqueues it for
+// delivery, returning 202 Accepted with the job ID immediately rather
+// than waiting for the send to complete. Poll GET /messages/{id} for the
+// outcome. from is the address this deployment sends as; if the caller
+// authenticated with an API key, that key's AllowedFrom restricts
+// whether it may use it.
+func SendMessageHandler(q *queue.Queue, audit *auth.AuditLog, from string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
 
-	if len(message.Body) > 1000 {
-		return errors.New("invalid message: body is too long")
-	}
+		defer r.Body.Close()
 
-	return nil
-}
+		var message Message
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+			return
+		}
 
-type MessageService struct {
-	smtpHost string
-	smtpPort int
-	username string
-	password string
-	from     string
-	timeout  time.Duration
-}
+		if err := validateMessage(message); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
 
-func NewSMTPMessageService(host string, port int, username, password, from string) *MessageService {
-	return &MessageService{
-		smtpHost: host,
-		smtpPort: port,
-		username: username,
-		password: password,
-		from:     from,
-		timeout:  10 * time.Second,
+		enqueueAndRespond(w, r, q, audit, from, message)
 	}
 }
 
-func (s *MessageService) Send(ctx context.Context, message Message) error {
-	ctx, cancel := context.WithTimeout(ctx, s.timeout)
-	defer cancel()
+// enqueueAndRespond is the tail end shared by SendMessageHandler and
+// SendTemplateHandler: check the caller's API key against from, enqueue
+// the already-validated message, audit the attempt, and respond.
+func enqueueAndRespond(w http.ResponseWriter, r *http.Request, q *queue.Queue, audit *auth.AuditLog, from string, message Message) {
+	logger := loggerFromContext(r.Context())
+	if len(message.To) > 0 {
+		logger = logger.With("recipient_domain", domainOf(message.To[0]))
+	}
+
+	var ownerID string
+	if key, ok := auth.FromContext(r.Context()); ok {
+		ownerID = key.ID
+		if !key.AllowsFrom(from) {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "api key is not allowed to send as this address"})
+			return
+		}
+	}
 
-	return sendGmail(ctx, s.smtpHost, s.smtpPort, s.username, s.password,
-		s.from, message.Email, message.Subject, message.Body)
+	job, err := q.Enqueue(r.Context(), message, ownerID)
+	result := "queued"
+	if err != nil {
+		result = "error: " + err.Error()
+	}
+	if auditErr := audit.Record(ownerID, strings.Join(message.To, ","), message.Subject, result); auditErr != nil {
+		logger.Error("audit log write failed", "error", auditErr)
+	}
+
+	if err != nil {
+		logger.Error("enqueue failed", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to queue message"})
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued", "id": job.ID})
 }
 
-func writeJSON(w http.ResponseWriter, status int, v any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(v)
+// templateSendRequest is the body of POST /send/template.
+type templateSendRequest struct {
+	Template string            `json:"template"`
+	Data     any               `json:"data"`
+	To       []string          `json:"to"`
+	Cc       []string          `json:"cc,omitempty"`
+	Bcc      []string          `json:"bcc,omitempty"`
+	ReplyTo  string            `json:"reply_to,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
 }
 
-func SendMessageHandler(svc *MessageService) http.HandlerFunc {
+// SendTemplateHandler renders the named template against Data, builds a
+// Message from the result, and enqueues it exactly like
+// SendMessageHandler.
+func SendTemplateHandler(tm *templates.Manager, q *queue.Queue, audit *auth.AuditLog, from string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
@@ -149,50 +243,317 @@ func SendMessageHandler(svc *MessageService) http.HandlerFunc {
 
 		defer r.Body.Close()
 
-		var message Message
-		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		var req templateSendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
 			return
 		}
+		if req.Template == "" || len(req.To) == 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "template and to are required"})
+			return
+		}
 
-		if err := validateMessage(message); err != nil {
+		rendered, err := tm.Render(req.Template, req.Data)
+		if err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
 
-		if err := svc.Send(r.Context(), message); err != nil {
-			log.Printf("SEND ERROR: %v\n", err)
-			writeJSON(w, http.StatusBadGateway, map[string]string{"error": "failed to send email"})
+		message := Message{
+			To:      req.To,
+			Cc:      req.Cc,
+			Bcc:     req.Bcc,
+			ReplyTo: req.ReplyTo,
+			Subject: rendered.Subject,
+			Body:    rendered.Text,
+			Headers: req.Headers,
+		}
+		if rendered.HasHTML {
+			message.HTMLBody = rendered.HTML
+		}
+
+		if err := validateMessage(message); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
 			return
 		}
 
-		writeJSON(w, http.StatusOK, map[string]string{"status": "sent"})
+		enqueueAndRespond(w, r, q, audit, from, message)
 	}
 }
 
-func ReceiveMessage(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
+// ReceiveMessageHandler serves both sides of /receive: GET lists messages
+// that have come in over the inbound SMTP server, and POST is a testing
+// shim that feeds a JSON-encoded Message through the same Receiver the
+// SMTP server uses, so both paths are exercised identically.
+func ReceiveMessageHandler(receiver inbound.Receiver, store *inbound.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, store.List())
+
+		case http.MethodPost:
+			defer r.Body.Close()
+
+			var m Message
+			if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+				return
+			}
+
+			msg := inbound.Message{
+				To:         m.To,
+				Subject:    m.Subject,
+				TextBody:   m.Body,
+				HTMLBody:   m.HTMLBody,
+				ReceivedAt: time.Now(),
+			}
+			if err := receiver.Receive(r.Context(), msg); err != nil {
+				loggerFromContext(r.Context()).Error("record inbound message failed", "error", err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to record message"})
+				return
+			}
+
+			writeJSON(w, http.StatusOK, map[string]string{"status": "received"})
+
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	}
+}
+
+// buildTransport wires up the outbound Transport from environment
+// configuration. SEND_TRANSPORT selects "smtp" (default), "smtps", or
+// "multi" (comma-separated SEND_TRANSPORT_ORDER of the above plus
+// "sendgrid"/"ses", tried in order with automatic failover). It returns
+// an error if SEND_TRANSPORT_ORDER names a backend that isn't actually
+// configured, rather than letting that surface as a nil-transport panic
+// on the first send.
+func buildTransport() (transport.Transport, error) {
+	smtpT := transport.NewSMTPTransport(
+		smtpHost(), smtpPort(),
+		os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"),
+	)
+
+	available := map[string]transport.Transport{"smtp": smtpT}
+
+	if host := os.Getenv("SMTPS_HOST"); host != "" {
+		port := 465
+		if p, err := strconv.Atoi(os.Getenv("SMTPS_PORT")); err == nil && p != 0 {
+			port = p
+		}
+		available["smtps"] = transport.NewSMTPSTransport(
+			host, port,
+			os.Getenv("SMTPS_USERNAME"), os.Getenv("SMTPS_PASSWORD"), os.Getenv("SMTP_FROM"),
+			transport.AuthMethod(os.Getenv("SMTPS_AUTH_METHOD")),
+		)
+	}
+
+	if apiKey := os.Getenv("SENDGRID_API_KEY"); apiKey != "" {
+		available["sendgrid"] = transport.NewSendGridTransport(apiKey, os.Getenv("SMTP_FROM"))
+	}
+
+	if region := os.Getenv("SES_REGION"); region != "" {
+		available["ses"] = transport.NewSESTransport(
+			region, os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), os.Getenv("SMTP_FROM"),
+		)
+	}
+
+	order := transportOrder()
+
+	for _, name := range order {
+		if _, ok := available[name]; !ok {
+			return nil, fmt.Errorf("transport %q in SEND_TRANSPORT_ORDER is not configured", name)
+		}
+	}
+
+	if len(order) == 1 {
+		return available[order[0]], nil
+	}
+
+	return transport.NewMultiTransport(30*time.Second, available, order), nil
+}
+
+// transportOrder returns the same SEND_TRANSPORT_ORDER/SEND_TRANSPORT
+// selection buildTransport uses, so other callers (readyzHandler) can
+// tell which backends are actually in play without rebuilding one.
+func transportOrder() []string {
+	if v := os.Getenv("SEND_TRANSPORT_ORDER"); v != "" {
+		return regexp.MustCompile(`\s*,\s*`).Split(v, -1)
+	}
+	if single := os.Getenv("SEND_TRANSPORT"); single != "" {
+		return []string{single}
+	}
+	return []string{"smtp"}
+}
+
+// usesSMTPHost reports whether the configured transport order includes
+// plain SMTP, i.e. whether smtpHost()/smtpPort() name a host readyzHandler
+// should actually be checking.
+func usesSMTPHost() bool {
+	for _, t := range transportOrder() {
+		if t == "smtp" {
+			return true
+		}
+	}
+	return false
+}
+
+// smtpHost and smtpPort are also used by checkSMTPReady for /readyz, so
+// that readiness reflects the same upstream the default transport talks
+// to.
+func smtpHost() string {
+	if h := os.Getenv("SMTP_HOST"); h != "" {
+		return h
+	}
+	return "smtp.gmail.com"
+}
+
+func smtpPort() int {
+	return envInt("SMTP_PORT", 587)
+}
+
+// checkSMTPReady dials the configured SMTP host and runs STARTTLS,
+// bounded by a short timeout, so /readyz reflects whether the upstream
+// we actually send through is reachable rather than just whether this
+// process is alive.
+func checkSMTPReady(ctx context.Context) error {
+	addr := fmt.Sprintf("%s:%d", smtpHost(), smtpPort())
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+
+	client, err := smtp.NewClient(conn, smtpHost())
+	if err != nil {
+		return fmt.Errorf("smtp handshake with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if err := client.StartTLS(&tls.Config{ServerName: smtpHost()}); err != nil {
+		return fmt.Errorf("starttls with %s: %w", addr, err)
 	}
 
-	defer r.Body.Close()
+	return nil
+}
+
+// healthzHandler reports process liveness: if this handler runs at all,
+// the process is up.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
 
-	var m Message
-	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+// readyzHandler reports whether the configured upstream SMTP host is
+// actually reachable, so orchestrators can hold traffic until a send
+// would stand a chance of succeeding. Deployments that send exclusively
+// through SMTPS, SendGrid, or SES have no plain-SMTP host to probe, so
+// readiness falls back to liveness for them.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if !usesSMTPHost() {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
 		return
 	}
 
-	log.Printf("RECEIVED: %+v\n", m)
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "received"})
+	if err := checkSMTPReady(r.Context()); err != nil {
+		loggerFromContext(r.Context()).Warn("readyz check failed", "error", err)
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready", "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// buildQueue opens the durable job store and wires up the send queue
+// that backs /send and /messages.
+func buildQueue() (*queue.Queue, *queue.BoltStore, error) {
+	dbPath := os.Getenv("QUEUE_DB_PATH")
+	if dbPath == "" {
+		dbPath = "queue.db"
+	}
+
+	store, err := queue.OpenBoltStore(dbPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open queue store: %w", err)
+	}
+
+	workers := envInt("QUEUE_WORKERS", 4)
+	maxAttempts := envInt("QUEUE_MAX_ATTEMPTS", 5)
+	ratePerSec := envFloat("QUEUE_RATE_PER_SEC", 5)
+	burst := envInt("QUEUE_RATE_BURST", 10)
+
+	limiters := queue.NewRateLimiters(ratePerSec, burst)
+
+	t, err := buildTransport()
+	if err != nil {
+		return nil, nil, fmt.Errorf("build transport: %w", err)
+	}
+	q := queue.New(store, t, limiters, "default", workers, maxAttempts)
+
+	return q, store, nil
+}
+
+func envInt(key string, def int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func envFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// buildTemplateManager loads the template directory configured by
+// TEMPLATES_DIR (default "templates"), creating it if missing.
+// TEMPLATES_DEV=1 enables mtime-based auto-reload for local development.
+func buildTemplateManager() (*templates.Manager, error) {
+	dir := os.Getenv("TEMPLATES_DIR")
+	if dir == "" {
+		dir = "templates"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create templates dir: %w", err)
+	}
+
+	dev := os.Getenv("TEMPLATES_DEV") == "1"
+	return templates.NewManager(dir, dev)
+}
+
+func keysDBPath() string {
+	if p := os.Getenv("KEYS_DB_PATH"); p != "" {
+		return p
+	}
+	return "keys.db"
+}
+
+func inboundDBPath() string {
+	if p := os.Getenv("INBOUND_DB_PATH"); p != "" {
+		return p
+	}
+	return "inbound.db"
 }
 
 func main() {
-	smtpUsername := os.Getenv("SMTP_USERNAME")
-	smtpPassword := os.Getenv("SMTP_PASSWORD")
-	smtpFrom := os.Getenv("SMTP_FROM")
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeysCLI(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	c := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
@@ -204,14 +565,202 @@ func main() {
 		AllowedHeaders: []string{"*"},
 	})
 
-	svc := NewSMTPMessageService("smtp.gmail.com", 587, smtpUsername, smtpPassword, smtpFrom)
+	q, store, err := buildQueue()
+	if err != nil {
+		slog.Error("configure send queue", "error", err)
+		os.Exit(1)
+	}
+	defer store.Close()
+
+	keyStore, err := auth.OpenStore(keysDBPath())
+	if err != nil {
+		slog.Error("open api key store", "error", err)
+		os.Exit(1)
+	}
+	defer keyStore.Close()
+
+	auditPath := os.Getenv("AUDIT_LOG_PATH")
+	if auditPath == "" {
+		auditPath = "audit.log"
+	}
+	auditLog, err := auth.OpenAuditLog(auditPath)
+	if err != nil {
+		slog.Error("open audit log", "error", err)
+		os.Exit(1)
+	}
+	defer auditLog.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	// Workers get their own context rather than the signal one, so sends
+	// already in flight at SIGTERM finish; q.Shutdown stops the workers.
+	if err := q.Start(context.Background()); err != nil {
+		slog.Error("start send queue", "error", err)
+		os.Exit(1)
+	}
+
+	inboundStore, err := inbound.NewStore(inboundDBPath())
+	if err != nil {
+		slog.Error("open inbound message store", "error", err)
+		os.Exit(1)
+	}
+	defer inboundStore.Close()
+
+	inboundCfg := inbound.DefaultConfig()
+	if addr := os.Getenv("INBOUND_SMTP_ADDR"); addr != "" {
+		inboundCfg.Addr = addr
+	}
+	inboundCfg.AllowedDomain = os.Getenv("INBOUND_ALLOWED_DOMAIN")
+	if pattern := os.Getenv("INBOUND_RECIPIENT_ALLOW"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			slog.Error("invalid INBOUND_RECIPIENT_ALLOW pattern", "error", err)
+			os.Exit(1)
+		}
+		inboundCfg.RecipientAllow = re
+	}
+	inboundCfg.TLSCertFile = os.Getenv("INBOUND_TLS_CERT")
+	inboundCfg.TLSKeyFile = os.Getenv("INBOUND_TLS_KEY")
+
+	smtpServer, err := inbound.Server(inboundCfg, inboundStore)
+	if err != nil {
+		slog.Error("configure inbound smtp server", "error", err)
+		os.Exit(1)
+	}
+
+	go func() {
+		slog.Info("inbound smtp server starting", "addr", inboundCfg.Addr)
+		if err := smtpServer.ListenAndServe(); err != nil {
+			slog.Error("inbound smtp server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	templateManager, err := buildTemplateManager()
+	if err != nil {
+		slog.Error("load templates", "error", err)
+		os.Exit(1)
+	}
+
+	// Each route group gets its own Middleware instance (and so its own
+	// quotas bucket) scoped to the narrowest permission it needs, so
+	// polling /messages for a job's outcome can't rate-limit a key out
+	// of actually sending mail.
+	requireSend := auth.Middleware(keyStore, auth.ScopeSend)
+	requireMessagesRead := auth.Middleware(keyStore, auth.ScopeMessagesRead)
+	requireTemplatesAdmin := auth.Middleware(keyStore, auth.ScopeTemplatesAdmin)
+	smtpFrom := os.Getenv("SMTP_FROM")
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/send", SendMessageHandler(svc))
-	mux.HandleFunc("/receive", ReceiveMessage)
+	mux.Handle("/send", metrics.Instrument("/send", requireSend(SendMessageHandler(q, auditLog, smtpFrom))))
+	mux.Handle("/send/template", metrics.Instrument("/send/template", requireSend(SendTemplateHandler(templateManager, q, auditLog, smtpFrom))))
+	mux.Handle("/receive", metrics.Instrument("/receive", requireMessagesRead(ReceiveMessageHandler(inboundStore, inboundStore))))
+	mux.Handle("/messages", metrics.Instrument("/messages", requireMessagesRead(queue.Handler(q))))
+	mux.Handle("/messages/", metrics.Instrument("/messages/", requireMessagesRead(queue.Handler(q))))
+	mux.Handle("/templates", metrics.Instrument("/templates", requireTemplatesAdmin(templates.AdminHandler(templateManager))))
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.Handle("/metrics", metrics.Handler())
+
+	srv := &http.Server{Addr: ":8080", Handler: c.Handler(withRequestLogging(mux))}
+
+	go func() {
+		slog.Info("server starting", "addr", ":8080")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("http server failed", "error", err)
+			os.Exit(1)
+		}
+	}()
 
-	handler := c.Handler(mux)
+	<-ctx.Done()
+	slog.Info("shutting down: draining in-flight sends")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	log.Println("Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", handler))
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("http server shutdown", "error", err)
+	}
+	if err := smtpServer.Close(); err != nil {
+		slog.Error("inbound smtp server shutdown", "error", err)
+	}
+	if err := q.Shutdown(shutdownCtx); err != nil {
+		slog.Error("queue shutdown", "error", err)
+	}
+}
+
+// runKeysCLI implements `emailsender keys add|revoke|list`.
+func runKeysCLI(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: emailsender keys add|revoke|list")
+	}
+
+	keyStore, err := auth.OpenStore(keysDBPath())
+	if err != nil {
+		return fmt.Errorf("open api key store: %w", err)
+	}
+	defer keyStore.Close()
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("keys add", flag.ExitOnError)
+		owner := fs.String("owner", "", "owner of this key")
+		from := fs.String("allowed-from", "", "comma-separated allowed From addresses (empty = any)")
+		scopes := fs.String("scopes", auth.ScopeSend, "comma-separated scopes")
+		perMinute := fs.Int("per-minute", 60, "per-minute send quota")
+		perDay := fs.Int("per-day", 10000, "per-day send quota")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *owner == "" {
+			return errors.New("--owner is required")
+		}
+
+		var allowedFrom []string
+		if *from != "" {
+			allowedFrom = strings.Split(*from, ",")
+		}
+
+		secret, key, err := auth.AddKey(keyStore, *owner, allowedFrom, *perMinute, *perDay, strings.Split(*scopes, ","))
+		if err != nil {
+			return fmt.Errorf("add key: %w", err)
+		}
+
+		fmt.Printf("id:     %s\nowner:  %s\nsecret: %s  (shown once; store it now)\n", key.ID, key.Owner, secret)
+		return nil
+
+	case "revoke":
+		fs := flag.NewFlagSet("keys revoke", flag.ExitOnError)
+		id := fs.String("id", "", "key id to revoke")
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *id == "" {
+			return errors.New("--id is required")
+		}
+		if err := auth.RevokeKey(keyStore, *id); err != nil {
+			return fmt.Errorf("revoke key: %w", err)
+		}
+		fmt.Printf("revoked %s\n", *id)
+		return nil
+
+	case "list":
+		keys, err := auth.ListKeys(keyStore)
+		if err != nil {
+			return fmt.Errorf("list keys: %w", err)
+		}
+		for _, k := range keys {
+			status := "active"
+			if k.Revoked() {
+				status = "revoked"
+			}
+			fmt.Printf("%s\towner=%s\tstatus=%s\tper_minute=%d\tper_day=%d\tscopes=%s\n",
+				k.ID, k.Owner, status, k.PerMinute, k.PerDay, strings.Join(k.Scopes, ","))
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown keys subcommand %q", args[0])
+	}
 }
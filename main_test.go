@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shokr12/Email_sender/mail"
+)
+
+func validMessage() Message {
+	return Message{
+		To:      []string{"to@example.com"},
+		Subject: "hello",
+		Body:    "body",
+	}
+}
+
+func TestValidateMessageRejectsCRLFInAttachment(t *testing.T) {
+	cases := []struct {
+		name string
+		att  mail.Attachment
+	}{
+		{"filename", mail.Attachment{Filename: "evil.txt\r\nX-Injected: pwned", ContentType: "text/plain"}},
+		{"content type", mail.Attachment{Filename: "evil.txt", ContentType: "text/plain\r\nX-Injected: pwned"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := validMessage()
+			m.Attachments = []mail.Attachment{tc.att}
+			if err := validateMessage(m); err == nil {
+				t.Fatalf("validateMessage with CRLF in attachment %s: want error, got nil", tc.name)
+			}
+		})
+	}
+}
+
+func TestValidateMessageAllowsCleanAttachment(t *testing.T) {
+	m := validMessage()
+	m.Attachments = []mail.Attachment{{Filename: "clean.txt", ContentType: "text/plain", Data: []byte("data")}}
+	if err := validateMessage(m); err != nil {
+		t.Fatalf("validateMessage with clean attachment: %v", err)
+	}
+}
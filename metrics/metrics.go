@@ -0,0 +1,77 @@
+// Package metrics holds the process's Prometheus collectors and exposes
+// them over /metrics. Other packages record against the package-level
+// vars directly rather than threading a collector through every
+// constructor, the same way the standard library's expvar is used.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// SendTotal counts every outbound send attempt by the transport that
+	// handled it and its outcome ("sent" or "error").
+	SendTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "email_send_total",
+		Help: "Total outbound send attempts, by transport and result.",
+	}, []string{"transport", "result"})
+
+	// SendDuration measures how long a single Transport.Send call took.
+	SendDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "email_send_duration_seconds",
+		Help:    "Time spent in a single Transport.Send call.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"transport"})
+
+	// QueueDepth tracks the number of jobs currently queued or sending.
+	QueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "email_queue_depth",
+		Help: "Number of jobs currently queued or in flight.",
+	})
+
+	// SMTPDialErrors counts failed attempts to open a connection to an
+	// upstream SMTP host, across both SMTPTransport and SMTPSTransport.
+	SMTPDialErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "smtp_dial_errors_total",
+		Help: "Total failures dialing an upstream SMTP host.",
+	})
+
+	// HTTPRequestsTotal counts HTTP requests this service served, by
+	// route, method, and response status code.
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total HTTP requests served, by route, method, and status code.",
+	}, []string{"route", "method", "status"})
+)
+
+// Handler serves the text-format Prometheus exposition for /metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Instrument wraps next so every request against it increments
+// HTTPRequestsTotal under the given route label (the registered mux
+// pattern, not the raw URL path, to keep cardinality bounded for routes
+// like /messages/{id}).
+func Instrument(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		HTTPRequestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(rec.status)).Inc()
+	})
+}
@@ -0,0 +1,18 @@
+package queue
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff returns the delay before retry attempt n (1-indexed): with d
+// exponential from base 1s and capped at max, a random value in
+// [d/2, d), so a batch of retries doesn't all wake up at once.
+func backoff(attempt int, max time.Duration) time.Duration {
+	d := time.Second << uint(attempt-1)
+	if d > max || d <= 0 {
+		d = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(d) / 2))
+	return d/2 + jitter
+}
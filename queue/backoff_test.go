@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffWithinBounds(t *testing.T) {
+	const max = 5 * time.Minute
+
+	for attempt := 1; attempt <= 20; attempt++ {
+		want := time.Second << uint(attempt-1)
+		if want > max || want <= 0 {
+			want = max
+		}
+
+		for i := 0; i < 20; i++ {
+			d := backoff(attempt, max)
+			if d < want/2 || d >= want {
+				t.Fatalf("attempt %d: backoff() = %v, want in [%v, %v)", attempt, d, want/2, want)
+			}
+		}
+	}
+}
+
+func TestBackoffCapsAtMax(t *testing.T) {
+	const max = 5 * time.Minute
+
+	// Attempt 30's exponential delay (1s<<29, about 17 years) is far past
+	// max, so it must fall back to max.
+	d := backoff(30, max)
+	if d < max/2 || d >= max {
+		t.Fatalf("backoff(30, max) = %v, want in [%v, %v)", d, max/2, max)
+	}
+}
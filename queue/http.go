@@ -0,0 +1,100 @@
+package queue
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/shokr12/Email_sender/auth"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// Handler serves the whole /messages subtree: GET /messages?state= lists
+// jobs, GET /messages/{id} returns one job's status, and
+// POST /messages/{id}/retry re-queues a dead-lettered job. Register it
+// for both "/messages" and "/messages/".
+func Handler(q *Queue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/messages")
+		rest = strings.Trim(rest, "/")
+
+		var owner string
+		if key, ok := auth.FromContext(r.Context()); ok {
+			owner = key.ID
+		}
+
+		switch {
+		case rest == "":
+			list(q, owner, w, r)
+
+		case strings.HasSuffix(rest, "/retry"):
+			retry(q, strings.TrimSuffix(rest, "/retry"), owner, w, r)
+
+		default:
+			status(q, rest, owner, w, r)
+		}
+	}
+}
+
+func list(q *Queue, owner string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	state := State(r.URL.Query().Get("state"))
+	jobs, err := q.List(state, owner)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list jobs"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func status(q *Queue, id, owner string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if id == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+
+	job, found, err := q.Get(id, owner)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load job"})
+		return
+	}
+	if !found {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "job not found"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
+
+func retry(q *Queue, id, owner string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if id == "" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+
+	job, err := q.Retry(r.Context(), id, owner)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, job)
+}
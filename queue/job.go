@@ -0,0 +1,42 @@
+// Package queue implements a durable outbound send queue: /send enqueues
+// a Job and returns immediately, a pool of workers drains the queue with
+// per-transport rate limiting and retries, and permanently-failed jobs
+// land in a dead-letter state for manual inspection or replay.
+package queue
+
+import (
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+)
+
+// State is where a Job currently sits in its lifecycle.
+type State string
+
+const (
+	StateQueued  State = "queued"
+	StateSending State = "sending"
+	StateSent    State = "sent"
+	StateFailed  State = "failed" // permanently failed; sits in the DLQ
+)
+
+// Job wraps a Message with the bookkeeping needed to send it durably:
+// its state, how many times delivery has been attempted, and the last
+// error encountered (if any).
+type Job struct {
+	ID        string       `json:"id"`
+	Message   mail.Message `json:"message"`
+	Transport string       `json:"transport,omitempty"`
+
+	// Owner is the identity (e.g. API key ID) that submitted this job,
+	// if the caller was authenticated.
+	Owner string `json:"owner,omitempty"`
+
+	State    State  `json:"state"`
+	Attempts int    `json:"attempts"`
+	LastErr  string `json:"last_error,omitempty"`
+
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+}
@@ -0,0 +1,286 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+	"github.com/shokr12/Email_sender/metrics"
+	"github.com/shokr12/Email_sender/transport"
+)
+
+// maxBackoff is the ceiling on the exponential retry delay.
+const maxBackoff = 5 * time.Minute
+
+// Queue is a durable, rate-limited outbound send queue. Enqueue persists
+// a Job and returns immediately; a pool of workers started by Start
+// drains it, retrying transient failures with backoff and moving
+// permanent failures to the dead-letter state.
+type Queue struct {
+	store     Store
+	transport transport.Transport
+	limiters  *RateLimiters
+	provider  string
+
+	maxAttempts int
+	workers     int
+
+	jobs chan Job
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New returns a Queue that sends through t, rate-limited per provider
+// via limiters (provider is the key used to look up t's bucket), with
+// workers concurrent senders and up to maxAttempts per job before it is
+// moved to the DLQ.
+func New(store Store, t transport.Transport, limiters *RateLimiters, provider string, workers, maxAttempts int) *Queue {
+	return &Queue{
+		store:       store,
+		transport:   t,
+		limiters:    limiters,
+		provider:    provider,
+		maxAttempts: maxAttempts,
+		workers:     workers,
+		jobs:        make(chan Job, 1024),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Enqueue persists msg as a new queued Job tagged with owner (the
+// authenticated caller's identity, or "" if unauthenticated) and
+// schedules it for delivery, returning immediately.
+func (q *Queue) Enqueue(ctx context.Context, msg mail.Message, owner string) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, fmt.Errorf("generate job id: %w", err)
+	}
+
+	now := time.Now()
+	job := Job{
+		ID:        id,
+		Message:   msg,
+		Owner:     owner,
+		State:     StateQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := q.store.Put(job); err != nil {
+		return Job{}, fmt.Errorf("persist job: %w", err)
+	}
+	metrics.QueueDepth.Inc()
+
+	select {
+	case q.jobs <- job:
+	default:
+		// Channel full: the job is durably queued regardless and will
+		// be picked up by the next Start (or the next time a
+		// worker frees up, if we're still running).
+		go func() {
+			select {
+			case q.jobs <- job:
+			case <-q.stop:
+			}
+		}()
+	}
+
+	return job, nil
+}
+
+// Start launches the worker pool and requeues any job left in the
+// queued or sending state from a previous run (e.g. after a crash).
+func (q *Queue) Start(ctx context.Context) error {
+	pending, err := q.store.All()
+	if err != nil {
+		return fmt.Errorf("load pending jobs: %w", err)
+	}
+	for _, job := range pending {
+		if job.State == StateQueued || job.State == StateSending {
+			job.State = StateQueued
+			q.jobs <- job
+			metrics.QueueDepth.Inc()
+		}
+	}
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+	return nil
+}
+
+// Shutdown stops workers from picking up new jobs and waits (up to
+// ctx's deadline) for in-flight sends to finish.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	close(q.stop)
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case job := <-q.jobs:
+			q.process(ctx, job)
+		}
+	}
+}
+
+func (q *Queue) process(ctx context.Context, job Job) {
+	for {
+		job.State = StateSending
+		job.Attempts++
+		job.UpdatedAt = time.Now()
+		_ = q.store.Put(job)
+
+		if err := q.limiters.Wait(ctx, q.provider); err != nil {
+			return
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		err := q.transport.Send(sendCtx, job.Message)
+		cancel()
+
+		if err == nil {
+			job.State = StateSent
+			job.LastErr = ""
+			job.UpdatedAt = time.Now()
+			_ = q.store.Put(job)
+			metrics.QueueDepth.Dec()
+			return
+		}
+
+		job.LastErr = err.Error()
+		job.UpdatedAt = time.Now()
+
+		if !transport.IsTemporary(err) || job.Attempts >= q.maxAttempts {
+			job.State = StateFailed
+			_ = q.store.Put(job)
+			metrics.QueueDepth.Dec()
+			slog.Error("job permanently failed", "job_id", job.ID, "attempts", job.Attempts, "error", err)
+			return
+		}
+
+		job.State = StateQueued
+		delay := backoff(job.Attempts, maxBackoff)
+		job.NextAttemptAt = time.Now().Add(delay)
+		_ = q.store.Put(job)
+
+		select {
+		case <-time.After(delay):
+		case <-q.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Retry re-queues a job currently in the failed (DLQ) state. owner, if
+// non-empty, must match the job's Owner or Retry reports it not found,
+// the same way Get does, so one tenant can't probe or resurrect
+// another's dead-lettered mail.
+func (q *Queue) Retry(ctx context.Context, id, owner string) (Job, error) {
+	job, found, err := q.store.Get(id)
+	if err != nil {
+		return Job{}, err
+	}
+	if !found || (owner != "" && job.Owner != owner) {
+		return Job{}, fmt.Errorf("job %s not found", id)
+	}
+	if job.State != StateFailed {
+		return Job{}, fmt.Errorf("job %s is not in the dead-letter state (currently %s)", id, job.State)
+	}
+
+	job.State = StateQueued
+	job.Attempts = 0
+	job.LastErr = ""
+	job.UpdatedAt = time.Now()
+	if err := q.store.Put(job); err != nil {
+		return Job{}, err
+	}
+	metrics.QueueDepth.Inc()
+
+	select {
+	case q.jobs <- job:
+	case <-q.stop:
+	}
+
+	return job, nil
+}
+
+// Get returns the current state of a job by ID. owner, if non-empty,
+// must match the job's Owner or Get reports it not found, so one
+// tenant can't fetch another's job by guessing/enumerating IDs.
+func (q *Queue) Get(id, owner string) (Job, bool, error) {
+	job, found, err := q.store.Get(id)
+	if err != nil || !found {
+		return job, found, err
+	}
+	if owner != "" && job.Owner != owner {
+		return Job{}, false, nil
+	}
+	return job, true, nil
+}
+
+// List returns every job in the given state, or every job if state is
+// empty. owner, if non-empty, further restricts the result to jobs that
+// caller submitted, so one tenant's /messages listing can't see another
+// tenant's recipients and subjects.
+func (q *Queue) List(state State, owner string) ([]Job, error) {
+	var (
+		jobs []Job
+		err  error
+	)
+	if state == "" {
+		jobs, err = q.store.All()
+	} else {
+		jobs, err = q.store.List(state)
+	}
+	if err != nil || owner == "" {
+		return jobs, err
+	}
+
+	filtered := make([]Job, 0, len(jobs))
+	for _, j := range jobs {
+		if j.Owner == owner {
+			filtered = append(filtered, j)
+		}
+	}
+	return filtered, nil
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
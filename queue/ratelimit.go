@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimiters hands out a token-bucket rate.Limiter per provider name,
+// creating one on first use from a default (rate, burst) pair.
+type RateLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	rps      float64
+	burst    int
+}
+
+// NewRateLimiters returns a RateLimiters where each provider gets its own
+// limiter allowing rps sends/sec with the given burst.
+func NewRateLimiters(rps float64, burst int) *RateLimiters {
+	return &RateLimiters{
+		limiters: make(map[string]*rate.Limiter),
+		rps:      rps,
+		burst:    burst,
+	}
+}
+
+func (r *RateLimiters) limiterFor(provider string) *rate.Limiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, ok := r.limiters[provider]
+	if !ok {
+		l = rate.NewLimiter(rate.Limit(r.rps), r.burst)
+		r.limiters[provider] = l
+	}
+	return l
+}
+
+// Wait blocks until provider's bucket has a token to spend, or ctx is
+// done.
+func (r *RateLimiters) Wait(ctx context.Context, provider string) error {
+	return r.limiterFor(provider).Wait(ctx)
+}
@@ -0,0 +1,111 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var jobsBucket = []byte("jobs")
+
+// Store persists Jobs durably so queued sends survive a restart.
+type Store interface {
+	Put(job Job) error
+	Get(id string) (Job, bool, error)
+	List(state State) ([]Job, error)
+	All() ([]Job, error)
+}
+
+// BoltStore is a Store backed by a single BoltDB file.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// OpenBoltStore opens (creating if necessary) a BoltDB file at path and
+// ensures the jobs bucket exists.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("open bolt db: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create jobs bucket: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// Close closes the underlying BoltDB file.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// Put implements Store.
+func (s *BoltStore) Put(job Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("encode job: %w", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).Put([]byte(job.ID), data)
+	})
+}
+
+// Get implements Store.
+func (s *BoltStore) Get(id string) (Job, bool, error) {
+	var job Job
+	var found bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(jobsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &job)
+	})
+
+	return job, found, err
+}
+
+// List implements Store, returning every job in the given state.
+func (s *BoltStore) List(state State) ([]Job, error) {
+	all, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []Job
+	for _, j := range all {
+		if j.State == state {
+			matched = append(matched, j)
+		}
+	}
+	return matched, nil
+}
+
+// All implements Store, returning every job regardless of state.
+func (s *BoltStore) All() ([]Job, error) {
+	var jobs []Job
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(_, data []byte) error {
+			var job Job
+			if err := json.Unmarshal(data, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	})
+
+	return jobs, err
+}
@@ -0,0 +1,81 @@
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Put creates or overwrites the template named name: its .txt body is
+// required, .html and a default subject are optional. It reloads the
+// in-memory cache after writing, so the next Render call sees the
+// change whether or not dev mode is on.
+func (m *Manager) Put(name, text, html, subject string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(m.dir, name+".txt"), []byte(text), 0o644); err != nil {
+		return fmt.Errorf("write %s.txt: %w", name, err)
+	}
+
+	if html != "" {
+		if err := os.WriteFile(filepath.Join(m.dir, name+".html"), []byte(html), 0o644); err != nil {
+			return fmt.Errorf("write %s.html: %w", name, err)
+		}
+	}
+
+	if subject != "" {
+		if err := os.WriteFile(filepath.Join(m.dir, name+".subject"), []byte(subject), 0o644); err != nil {
+			return fmt.Errorf("write %s.subject: %w", name, err)
+		}
+	}
+
+	return m.reload()
+}
+
+// Delete removes every file belonging to the named template.
+func (m *Manager) Delete(name string) error {
+	if err := validateName(name); err != nil {
+		return err
+	}
+
+	for _, ext := range []string{".txt", ".html", ".subject"} {
+		path := filepath.Join(m.dir, name+ext)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+	}
+	return m.reload()
+}
+
+// validateName rejects a template name that could escape m.dir via
+// filepath.Join, e.g. "../../etc/passwd".
+func validateName(name string) error {
+	if name == "" || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid template name %q", name)
+	}
+	return nil
+}
+
+// List returns the names of every template (derived from its .txt
+// file).
+func (m *Manager) List() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(m.dir, "*.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(matches))
+	for i, path := range matches {
+		names[i] = strings.TrimSuffix(filepath.Base(path), ".txt")
+	}
+	return names, nil
+}
+
+// Reload forces an immediate re-parse of every template, independent of
+// dev mode.
+func (m *Manager) Reload() error {
+	return m.reload()
+}
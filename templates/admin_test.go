@@ -0,0 +1,59 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(dir, false)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	outside := filepath.Join(t.TempDir(), "canary.txt")
+	if err := os.WriteFile(outside, []byte("do not delete me"), 0o644); err != nil {
+		t.Fatalf("write canary file: %v", err)
+	}
+
+	traversal := "../" + filepath.Base(filepath.Dir(outside)) + "/" + "canary"
+	if err := m.Delete(traversal); err == nil {
+		t.Fatalf("Delete(%q): want error for path traversal, got nil", traversal)
+	}
+
+	if _, err := os.Stat(outside); err != nil {
+		t.Fatalf("canary file was removed despite rejected Delete: %v", err)
+	}
+}
+
+func TestPutThenDeleteRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(dir, false)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	if err := m.Put("welcome", "hello {{.Name}}", "", "Welcome"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	names, err := m.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(names) != 1 || names[0] != "welcome" {
+		t.Fatalf("List() = %v, want [welcome]", names)
+	}
+
+	if err := m.Delete("welcome"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	names, err = m.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("List() after Delete = %v, want empty", names)
+	}
+}
@@ -0,0 +1,70 @@
+package templates
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+type putRequest struct {
+	Name    string `json:"name"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+	Subject string `json:"subject,omitempty"`
+}
+
+// AdminHandler serves POST /templates (create/update), GET /templates
+// (list), and DELETE /templates?name= (remove).
+func AdminHandler(m *Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			defer r.Body.Close()
+
+			var req putRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON body"})
+				return
+			}
+			if req.Name == "" || req.Text == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name and text are required"})
+				return
+			}
+
+			if err := m.Put(req.Name, req.Text, req.HTML, req.Subject); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+
+			writeJSON(w, http.StatusOK, map[string]string{"status": "saved", "name": req.Name})
+
+		case http.MethodGet:
+			names, err := m.List()
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list templates"})
+				return
+			}
+			writeJSON(w, http.StatusOK, names)
+
+		case http.MethodDelete:
+			name := r.URL.Query().Get("name")
+			if name == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name is required"})
+				return
+			}
+			if err := m.Delete(name); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete template"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"status": "deleted", "name": name})
+
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		}
+	}
+}
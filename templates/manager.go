@@ -0,0 +1,188 @@
+// Package templates renders outbound messages from named template pairs
+// (a .txt and an optional .html file, sharing a directory so one can
+// {{template}} into another as a partial or layout) plus a per-template
+// default subject line.
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+)
+
+// Rendered is the output of rendering a template against data: a
+// subject and a plain-text body, plus an HTML body if the template had
+// one.
+type Rendered struct {
+	Subject string
+	Text    string
+	HTML    string
+	HasHTML bool
+}
+
+// Manager loads template pairs from a directory and renders them. In dev
+// mode it reloads automatically when a file in the directory changes;
+// otherwise templates are parsed once and cached.
+type Manager struct {
+	dir string
+	dev bool
+
+	mu       sync.RWMutex
+	text     *texttemplate.Template
+	html     *htmltemplate.Template
+	subjects map[string]string
+	loadedAt time.Time
+}
+
+// NewManager loads every *.txt/*.html/*.subject file in dir and returns
+// a Manager ready to render them. When dev is true, Render reloads the
+// set whenever a file under dir has changed since the last load.
+func NewManager(dir string, dev bool) (*Manager, error) {
+	m := &Manager{dir: dir, dev: dev}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Render executes the named template against data, returning its
+// subject, plain-text body, and (if the template pair includes one)
+// HTML body.
+func (m *Manager) Render(name string, data any) (Rendered, error) {
+	if m.dev {
+		if err := m.reloadIfChanged(); err != nil {
+			return Rendered{}, fmt.Errorf("reload templates: %w", err)
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	textTmpl := m.text.Lookup(name + ".txt")
+	if textTmpl == nil {
+		return Rendered{}, fmt.Errorf("template %q not found", name)
+	}
+
+	var textBuf bytes.Buffer
+	if err := textTmpl.Execute(&textBuf, data); err != nil {
+		return Rendered{}, fmt.Errorf("render %s.txt: %w", name, err)
+	}
+
+	out := Rendered{Text: textBuf.String()}
+
+	if htmlTmpl := m.html.Lookup(name + ".html"); htmlTmpl != nil {
+		var htmlBuf bytes.Buffer
+		if err := htmlTmpl.Execute(&htmlBuf, data); err != nil {
+			return Rendered{}, fmt.Errorf("render %s.html: %w", name, err)
+		}
+		out.HTML = htmlBuf.String()
+		out.HasHTML = true
+	}
+
+	if subj, ok := m.subjects[name]; ok {
+		subjTmpl, err := texttemplate.New("subject").Parse(subj)
+		if err != nil {
+			return Rendered{}, fmt.Errorf("parse subject for %s: %w", name, err)
+		}
+		var subjBuf bytes.Buffer
+		if err := subjTmpl.Execute(&subjBuf, data); err != nil {
+			return Rendered{}, fmt.Errorf("render subject for %s: %w", name, err)
+		}
+		out.Subject = subjBuf.String()
+	}
+
+	return out, nil
+}
+
+func (m *Manager) reload() error {
+	text := texttemplate.New("")
+	if matches, _ := filepath.Glob(filepath.Join(m.dir, "*.txt")); len(matches) > 0 {
+		var err error
+		text, err = texttemplate.ParseGlob(filepath.Join(m.dir, "*.txt"))
+		if err != nil {
+			return fmt.Errorf("parse text templates: %w", err)
+		}
+	}
+
+	html := htmltemplate.New("")
+	if matches, _ := filepath.Glob(filepath.Join(m.dir, "*.html")); len(matches) > 0 {
+		var err error
+		html, err = htmltemplate.ParseGlob(filepath.Join(m.dir, "*.html"))
+		if err != nil {
+			return fmt.Errorf("parse html templates: %w", err)
+		}
+	}
+
+	subjects, err := loadSubjects(m.dir)
+	if err != nil {
+		return fmt.Errorf("load subjects: %w", err)
+	}
+
+	m.mu.Lock()
+	m.text = text
+	m.html = html
+	m.subjects = subjects
+	m.loadedAt = time.Now()
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *Manager) reloadIfChanged() error {
+	latest, err := latestModTime(m.dir)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	stale := latest.After(m.loadedAt)
+	m.mu.RUnlock()
+
+	if !stale {
+		return nil
+	}
+	return m.reload()
+}
+
+func loadSubjects(dir string) (map[string]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.subject"))
+	if err != nil {
+		return nil, err
+	}
+
+	subjects := make(map[string]string, len(matches))
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".subject")
+		subjects[name] = strings.TrimRight(string(data), "\n")
+	}
+	return subjects, nil
+}
+
+func latestModTime(dir string) (time.Time, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var latest time.Time
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,102 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+)
+
+// breakerThreshold is the number of consecutive failures that trips a
+// transport's circuit breaker.
+const breakerThreshold = 3
+
+// candidate pairs a Transport with a name used for logging and health
+// tracking.
+type candidate struct {
+	name      string
+	transport Transport
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+func (c *candidate) available(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return now.After(c.openUntil)
+}
+
+func (c *candidate) recordSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+	c.openUntil = time.Time{}
+}
+
+func (c *candidate) recordFailure(now time.Time, cooldown time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	if c.failures >= breakerThreshold {
+		c.openUntil = now.Add(cooldown)
+	}
+}
+
+// MultiTransport tries a priority-ordered list of transports, skipping
+// any whose circuit breaker is currently open, and fails over to the
+// next candidate when one returns a transient error.
+type MultiTransport struct {
+	candidates []*candidate
+	cooldown   time.Duration
+}
+
+// NewMultiTransport builds a MultiTransport that tries transports in
+// order, with a circuit breaker cooldown of cooldown once a transport
+// has failed breakerThreshold times in a row.
+func NewMultiTransport(cooldown time.Duration, transports map[string]Transport, order []string) *MultiTransport {
+	m := &MultiTransport{cooldown: cooldown}
+	for _, name := range order {
+		m.candidates = append(m.candidates, &candidate{name: name, transport: transports[name]})
+	}
+	return m
+}
+
+// Send implements Transport by trying each candidate in priority order
+// until one succeeds, skipping candidates whose breaker is open and
+// failing over on transient errors. A permanent error from a candidate
+// is returned immediately without trying the rest.
+func (m *MultiTransport) Send(ctx context.Context, msg mail.Message) error {
+	now := time.Now()
+
+	var lastErr error
+	tried := false
+
+	for _, c := range m.candidates {
+		if !c.available(now) {
+			continue
+		}
+
+		tried = true
+		err := c.transport.Send(ctx, msg)
+		if err == nil {
+			c.recordSuccess()
+			return nil
+		}
+
+		if !IsTemporary(err) {
+			return fmt.Errorf("%s: %w", c.name, err)
+		}
+
+		c.recordFailure(now, m.cooldown)
+		lastErr = fmt.Errorf("%s: %w", c.name, err)
+	}
+
+	if !tried {
+		return Temporary(fmt.Errorf("all transports unavailable (circuit open)"))
+	}
+	return lastErr
+}
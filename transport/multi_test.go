@@ -0,0 +1,83 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+)
+
+// fakeTransport records every Send call and returns errs[i] on the i-th
+// call (the last error is reused once errs is exhausted).
+type fakeTransport struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeTransport) Send(ctx context.Context, msg mail.Message) error {
+	var err error
+	if f.calls < len(f.errs) {
+		err = f.errs[f.calls]
+	} else if len(f.errs) > 0 {
+		err = f.errs[len(f.errs)-1]
+	}
+	f.calls++
+	return err
+}
+
+func TestMultiTransportFailsOverOnTemporaryError(t *testing.T) {
+	primary := &fakeTransport{errs: []error{Temporary(errors.New("connection reset"))}}
+	backup := &fakeTransport{errs: []error{nil}}
+
+	m := NewMultiTransport(time.Minute, map[string]Transport{
+		"primary": primary,
+		"backup":  backup,
+	}, []string{"primary", "backup"})
+
+	if err := m.Send(context.Background(), mail.Message{}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if primary.calls != 1 || backup.calls != 1 {
+		t.Errorf("primary.calls = %d, backup.calls = %d, want 1 and 1", primary.calls, backup.calls)
+	}
+}
+
+func TestMultiTransportStopsOnPermanentError(t *testing.T) {
+	primary := &fakeTransport{errs: []error{errors.New("550 rejected")}}
+	backup := &fakeTransport{errs: []error{nil}}
+
+	m := NewMultiTransport(time.Minute, map[string]Transport{
+		"primary": primary,
+		"backup":  backup,
+	}, []string{"primary", "backup"})
+
+	if err := m.Send(context.Background(), mail.Message{}); err == nil {
+		t.Fatal("Send: want error for a permanent failure, got nil")
+	}
+	if backup.calls != 0 {
+		t.Errorf("backup.calls = %d, want 0 (permanent errors must not fail over)", backup.calls)
+	}
+}
+
+func TestMultiTransportOpensCircuitAfterRepeatedFailures(t *testing.T) {
+	primary := &fakeTransport{errs: []error{Temporary(errors.New("timeout"))}}
+	backup := &fakeTransport{errs: []error{nil}}
+
+	m := NewMultiTransport(time.Minute, map[string]Transport{
+		"primary": primary,
+		"backup":  backup,
+	}, []string{"primary", "backup"})
+
+	// One send past the threshold, so the last one finds the breaker open.
+	for i := 0; i < breakerThreshold+1; i++ {
+		if err := m.Send(context.Background(), mail.Message{}); err != nil {
+			t.Fatalf("Send %d: %v", i, err)
+		}
+	}
+
+	if primary.calls != breakerThreshold {
+		t.Errorf("primary.calls = %d, want %d (breaker should skip it once open)", primary.calls, breakerThreshold)
+	}
+}
@@ -0,0 +1,149 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+	"github.com/shokr12/Email_sender/metrics"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridTransport sends mail through SendGrid's HTTP v3 API.
+type SendGridTransport struct {
+	APIKey     string
+	From       string
+	HTTPClient *http.Client
+}
+
+// NewSendGridTransport returns a SendGridTransport with a 10s default
+// HTTP client timeout.
+func NewSendGridTransport(apiKey, from string) *SendGridTransport {
+	return &SendGridTransport{
+		APIKey:     apiKey,
+		From:       from,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Attachments      []sendGridAttachment      `json:"attachments,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To  []sendGridAddress `json:"to"`
+	Cc  []sendGridAddress `json:"cc,omitempty"`
+	Bcc []sendGridAddress `json:"bcc,omitempty"`
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// sendGridAttachment carries one attachment's content base64-encoded,
+// per SendGrid's v3 mail/send schema. Content marshals to base64
+// automatically since it's a []byte.
+type sendGridAttachment struct {
+	Content     []byte `json:"content"`
+	Type        string `json:"type,omitempty"`
+	Filename    string `json:"filename"`
+	Disposition string `json:"disposition"`
+}
+
+func sendGridAttachments(attachments []mail.Attachment) []sendGridAttachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	out := make([]sendGridAttachment, len(attachments))
+	for i, a := range attachments {
+		out[i] = sendGridAttachment{
+			Content:     a.Data,
+			Type:        a.ContentType,
+			Filename:    a.Filename,
+			Disposition: "attachment",
+		}
+	}
+	return out
+}
+
+func sendGridAddresses(emails []string) []sendGridAddress {
+	if len(emails) == 0 {
+		return nil
+	}
+	addrs := make([]sendGridAddress, len(emails))
+	for i, e := range emails {
+		addrs[i] = sendGridAddress{Email: e}
+	}
+	return addrs
+}
+
+// Send implements Transport.
+func (t *SendGridTransport) Send(ctx context.Context, msg mail.Message) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.SendDuration.WithLabelValues("sendgrid").Observe(time.Since(start).Seconds())
+		metrics.SendTotal.WithLabelValues("sendgrid", sendResult(err)).Inc()
+	}()
+
+	content := []sendGridContent{{Type: "text/plain", Value: msg.Body}}
+	if msg.HTMLBody != "" {
+		content = append(content, sendGridContent{Type: "text/html", Value: msg.HTMLBody})
+	}
+
+	payload := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{
+			To:  sendGridAddresses(msg.To),
+			Cc:  sendGridAddresses(msg.Cc),
+			Bcc: sendGridAddresses(msg.Bcc),
+		}},
+		From:        sendGridAddress{Email: t.From},
+		Subject:     msg.Subject,
+		Content:     content,
+		Attachments: sendGridAttachments(msg.Attachments),
+	}
+	if msg.ReplyTo != "" {
+		payload.ReplyTo = &sendGridAddress{Email: msg.ReplyTo}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode sendgrid request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build sendgrid request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+
+	resp, err := t.HTTPClient.Do(req)
+	if err != nil {
+		return Temporary(fmt.Errorf("sendgrid request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return Temporary(fmt.Errorf("sendgrid: %s", resp.Status))
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sendgrid: %s", resp.Status)
+	}
+
+	return nil
+}
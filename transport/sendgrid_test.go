@@ -0,0 +1,27 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/shokr12/Email_sender/mail"
+)
+
+func TestSendGridAttachments(t *testing.T) {
+	if got := sendGridAttachments(nil); got != nil {
+		t.Errorf("sendGridAttachments(nil) = %v, want nil", got)
+	}
+
+	in := []mail.Attachment{
+		{Filename: "a.txt", ContentType: "text/plain", Data: []byte("hello")},
+	}
+	out := sendGridAttachments(in)
+	if len(out) != 1 {
+		t.Fatalf("sendGridAttachments: got %d attachments, want 1", len(out))
+	}
+	if out[0].Filename != "a.txt" || out[0].Type != "text/plain" || string(out[0].Content) != "hello" {
+		t.Errorf("sendGridAttachments: got %+v, want filename=a.txt type=text/plain content=hello", out[0])
+	}
+	if out[0].Disposition != "attachment" {
+		t.Errorf("sendGridAttachments: Disposition = %q, want %q", out[0].Disposition, "attachment")
+	}
+}
@@ -0,0 +1,172 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+	"github.com/shokr12/Email_sender/metrics"
+)
+
+// SESTransport sends mail through the Amazon SES v2 SendEmail HTTP API,
+// signed with SigV4. It intentionally avoids pulling in the AWS SDK for
+// a single API call.
+type SESTransport struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	From            string
+	HTTPClient      *http.Client
+}
+
+// NewSESTransport returns an SESTransport with a 10s default HTTP client
+// timeout.
+func NewSESTransport(region, accessKeyID, secretAccessKey, from string) *SESTransport {
+	return &SESTransport{
+		Region:          region,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		From:            from,
+		HTTPClient:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// sesSendEmailRequest always uses SES's "Raw" content type rather than
+// "Simple": Simple has no way to carry attachments, Reply-To, or custom
+// headers, and mail.Message.Build already renders all of those into a
+// single RFC 5322 message we can hand over unchanged.
+type sesSendEmailRequest struct {
+	FromEmailAddress string             `json:"FromEmailAddress"`
+	Destination      sesDestination     `json:"Destination"`
+	Content          sesEmailContentRaw `json:"Content"`
+}
+
+type sesDestination struct {
+	ToAddresses  []string `json:"ToAddresses"`
+	CcAddresses  []string `json:"CcAddresses,omitempty"`
+	BccAddresses []string `json:"BccAddresses,omitempty"`
+}
+
+type sesEmailContentRaw struct {
+	Raw sesRawMessage `json:"Raw"`
+}
+
+// sesRawMessage's Data marshals to base64 automatically since it's a
+// []byte, which is what the Raw content type requires.
+type sesRawMessage struct {
+	Data []byte `json:"Data"`
+}
+
+// Send implements Transport.
+func (t *SESTransport) Send(ctx context.Context, msg mail.Message) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.SendDuration.WithLabelValues("ses").Observe(time.Since(start).Seconds())
+		metrics.SendTotal.WithLabelValues("ses", sendResult(err)).Inc()
+	}()
+
+	id, err := mail.NewMessageID()
+	if err != nil {
+		return fmt.Errorf("generate message id: %w", err)
+	}
+	raw, err := msg.Build(t.From, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("build raw message: %w", err)
+	}
+
+	payload := sesSendEmailRequest{
+		FromEmailAddress: t.From,
+		Destination: sesDestination{
+			ToAddresses:  msg.To,
+			CcAddresses:  msg.Cc,
+			BccAddresses: msg.Bcc,
+		},
+		Content: sesEmailContentRaw{Raw: sesRawMessage{Data: raw}},
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode ses request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("https://email.%s.amazonaws.com/v2/email/outbound-emails", t.Region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build ses request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if err := t.sign(req, body, time.Now().UTC()); err != nil {
+		return fmt.Errorf("sign ses request: %w", err)
+	}
+
+	resp, err := t.HTTPClient.Do(req)
+	if err != nil {
+		return Temporary(fmt.Errorf("ses request: %w", err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return Temporary(fmt.Errorf("ses: %s", resp.Status))
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("ses: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// sign applies AWS Signature Version 4 to req for the "ses" service.
+func (t *SESTransport) sign(req *http.Request, body []byte, now time.Time) error {
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("Host", req.URL.Host)
+
+	payloadHash := sha256Hex(body)
+	canonicalHeaders := fmt.Sprintf("content-type:%s\nhost:%s\nx-amz-date:%s\n",
+		req.Header.Get("Content-Type"), req.URL.Host, amzDate)
+	signedHeaders := "content-type;host;x-amz-date"
+
+	canonicalRequest := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s",
+		req.Method, req.URL.Path, "", canonicalHeaders, signedHeaders, payloadHash)
+
+	credentialScope := fmt.Sprintf("%s/%s/ses/aws4_request", dateStamp, t.Region)
+	stringToSign := fmt.Sprintf("AWS4-HMAC-SHA256\n%s\n%s\n%s",
+		amzDate, credentialScope, sha256Hex([]byte(canonicalRequest)))
+
+	signingKey := sesSigningKey(t.SecretAccessKey, dateStamp, t.Region)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+
+	authHeader := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.AccessKeyID, credentialScope, signedHeaders, signature)
+	req.Header.Set("Authorization", authHeader)
+
+	return nil
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func sesSigningKey(secret, dateStamp, region string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secret), []byte(dateStamp))
+	kRegion := hmacSHA256(kDate, []byte(region))
+	kService := hmacSHA256(kRegion, []byte("ses"))
+	return hmacSHA256(kService, []byte("aws4_request"))
+}
@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+)
+
+func TestSESPayloadCarriesFullRawMessage(t *testing.T) {
+	msg := mail.Message{
+		To:      []string{"to@example.com"},
+		Bcc:     []string{"bcc@example.com"},
+		ReplyTo: "reply@example.com",
+		Subject: "hello",
+		Body:    "plain body",
+		Attachments: []mail.Attachment{
+			{Filename: "a.txt", ContentType: "text/plain", Data: []byte("attachment data")},
+		},
+	}
+
+	raw, err := msg.Build("from@example.com", "msg1", time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	payload := sesSendEmailRequest{
+		FromEmailAddress: "from@example.com",
+		Destination: sesDestination{
+			ToAddresses:  msg.To,
+			BccAddresses: msg.Bcc,
+		},
+		Content: sesEmailContentRaw{Raw: sesRawMessage{Data: raw}},
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var decoded sesSendEmailRequest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if string(decoded.Content.Raw.Data) != string(raw) {
+		t.Errorf("round-tripped raw message doesn't match what Build produced")
+	}
+	if len(decoded.Destination.BccAddresses) != 1 || decoded.Destination.BccAddresses[0] != "bcc@example.com" {
+		t.Errorf("Destination.BccAddresses = %v, want [bcc@example.com]", decoded.Destination.BccAddresses)
+	}
+
+	full := string(decoded.Content.Raw.Data)
+	if !strings.Contains(full, "Reply-To: reply@example.com") {
+		t.Errorf("raw message missing Reply-To header:\n%s", full)
+	}
+	if !strings.Contains(full, `filename="a.txt"`) {
+		t.Errorf("raw message missing attachment:\n%s", full)
+	}
+}
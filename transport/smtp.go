@@ -0,0 +1,133 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/shokr12/Email_sender/mail"
+	"github.com/shokr12/Email_sender/metrics"
+)
+
+// errNoRecipients is returned when a Message has no To/Cc/Bcc at all.
+var errNoRecipients = fmt.Errorf("message has no recipients")
+
+// wrapSMTPErr classifies an in-transaction net/smtp error (Auth, Mail,
+// Rcpt, Data) by the SMTP reply code it carries: 4xx is temporary and
+// worth retrying/failing over, 5xx and anything else we can't classify
+// is permanent.
+func wrapSMTPErr(err error) error {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return classifyReply(protoErr.Code, err)
+	}
+	return err
+}
+
+// SMTPTransport sends mail via net/smtp with STARTTLS and PLAIN auth,
+// e.g. against smtp.gmail.com:587. This is the original transport this
+// service shipped with.
+type SMTPTransport struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// NewSMTPTransport returns an SMTPTransport with a 10s default timeout.
+func NewSMTPTransport(host string, port int, username, password, from string) *SMTPTransport {
+	return &SMTPTransport{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+		Timeout:  10 * time.Second,
+	}
+}
+
+// Send implements Transport.
+func (t *SMTPTransport) Send(ctx context.Context, msg mail.Message) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.SendDuration.WithLabelValues("smtp").Observe(time.Since(start).Seconds())
+		metrics.SendTotal.WithLabelValues("smtp", sendResult(err)).Inc()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	addr := fmt.Sprintf("%s:%d", t.Host, t.Port)
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		metrics.SMTPDialErrors.Inc()
+		return Temporary(fmt.Errorf("dial smtp: %w", err))
+	}
+
+	c, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		return Temporary(fmt.Errorf("new smtp client: %w", err))
+	}
+	defer c.Close()
+
+	tlsCfg := &tls.Config{ServerName: t.Host}
+	if err := c.StartTLS(tlsCfg); err != nil {
+		return Temporary(fmt.Errorf("starttls: %w", err))
+	}
+
+	auth := smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	if err := c.Auth(auth); err != nil {
+		return wrapSMTPErr(fmt.Errorf("auth: %w", err))
+	}
+
+	recipients := msg.Recipients()
+	if len(recipients) == 0 {
+		return errNoRecipients
+	}
+
+	if err := c.Mail(t.From); err != nil {
+		return wrapSMTPErr(fmt.Errorf("mail from: %w", err))
+	}
+
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt); err != nil {
+			return wrapSMTPErr(fmt.Errorf("rcpt to %s: %w", rcpt, err))
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return wrapSMTPErr(fmt.Errorf("data: %w", err))
+	}
+
+	id, err := mail.NewMessageID()
+	if err != nil {
+		_ = w.Close()
+		return fmt.Errorf("generate message id: %w", err)
+	}
+
+	body, err := msg.Build(t.From, id, time.Now())
+	if err != nil {
+		_ = w.Close()
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close data: %w", err)
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,145 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/emersion/go-sasl"
+	gosmtp "github.com/emersion/go-smtp"
+
+	"github.com/shokr12/Email_sender/mail"
+	"github.com/shokr12/Email_sender/metrics"
+)
+
+// AuthMethod selects the SASL mechanism SMTPSTransport authenticates
+// with.
+type AuthMethod string
+
+const (
+	AuthPlain   AuthMethod = "PLAIN"
+	AuthLogin   AuthMethod = "LOGIN"
+	AuthCRAMMD5 AuthMethod = "CRAM-MD5"
+)
+
+// SMTPSTransport sends mail over an implicit-TLS connection (the classic
+// port 465), using github.com/emersion/go-smtp as the client. Unlike
+// SMTPTransport it supports LOGIN and CRAM-MD5 in addition to PLAIN.
+type SMTPSTransport struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	Auth     AuthMethod
+	Timeout  time.Duration
+}
+
+// NewSMTPSTransport returns an SMTPSTransport with a 10s default timeout.
+func NewSMTPSTransport(host string, port int, username, password, from string, auth AuthMethod) *SMTPSTransport {
+	return &SMTPSTransport{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		From:     from,
+		Auth:     auth,
+		Timeout:  10 * time.Second,
+	}
+}
+
+// wrapSMTPSErr classifies an in-transaction go-smtp error (Auth, Mail,
+// Rcpt, Data) by the SMTP reply code it carries: 4xx is temporary and
+// worth retrying/failing over, 5xx and anything else we can't classify
+// is permanent.
+func wrapSMTPSErr(err error) error {
+	var smtpErr *gosmtp.SMTPError
+	if errors.As(err, &smtpErr) {
+		return classifyReply(smtpErr.Code, err)
+	}
+	return err
+}
+
+func (t *SMTPSTransport) saslClient() sasl.Client {
+	switch t.Auth {
+	case AuthLogin:
+		return sasl.NewLoginClient(t.Username, t.Password)
+	case AuthCRAMMD5:
+		return newCRAMMD5Client(t.Username, t.Password)
+	default:
+		return sasl.NewPlainClient("", t.Username, t.Password)
+	}
+}
+
+// Send implements Transport.
+func (t *SMTPSTransport) Send(ctx context.Context, msg mail.Message) (err error) {
+	start := time.Now()
+	defer func() {
+		metrics.SendDuration.WithLabelValues("smtps").Observe(time.Since(start).Seconds())
+		metrics.SendTotal.WithLabelValues("smtps", sendResult(err)).Inc()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	addr := fmt.Sprintf("%s:%d", t.Host, t.Port)
+
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: t.Host}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		metrics.SMTPDialErrors.Inc()
+		return Temporary(fmt.Errorf("dial smtps: %w", err))
+	}
+
+	c := gosmtp.NewClient(conn)
+	defer c.Close()
+
+	if err := c.Auth(t.saslClient()); err != nil {
+		return wrapSMTPSErr(fmt.Errorf("auth: %w", err))
+	}
+
+	recipients := msg.Recipients()
+	if len(recipients) == 0 {
+		return errNoRecipients
+	}
+
+	if err := c.Mail(t.From, nil); err != nil {
+		return wrapSMTPSErr(fmt.Errorf("mail from: %w", err))
+	}
+
+	for _, rcpt := range recipients {
+		if err := c.Rcpt(rcpt, nil); err != nil {
+			return wrapSMTPSErr(fmt.Errorf("rcpt to %s: %w", rcpt, err))
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return wrapSMTPSErr(fmt.Errorf("data: %w", err))
+	}
+
+	id, err := mail.NewMessageID()
+	if err != nil {
+		_ = w.Close()
+		return fmt.Errorf("generate message id: %w", err)
+	}
+
+	body, err := msg.Build(t.From, id, time.Now())
+	if err != nil {
+		_ = w.Close()
+		return fmt.Errorf("build message: %w", err)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("close data: %w", err)
+	}
+
+	return c.Quit()
+}
@@ -0,0 +1,78 @@
+// Package transport abstracts the act of actually sending a Message, so
+// callers (the send queue, in particular) can be configured with any
+// combination of SMTP, SMTPS, and HTTP-API providers instead of being
+// hard-coded to one.
+package transport
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shokr12/Email_sender/mail"
+)
+
+// Transport delivers a single Message. Implementations should return an
+// error that satisfies Temporary() bool (see IsTemporary) when the
+// failure is transient, so callers like MultiTransport know it's worth
+// failing over or retrying rather than giving up.
+type Transport interface {
+	Send(ctx context.Context, msg mail.Message) error
+}
+
+// temporary is implemented by errors that know whether they're worth
+// retrying.
+type temporary interface {
+	Temporary() bool
+}
+
+// IsTemporary reports whether err represents a transient failure (a
+// timeout, a connection reset, a 4xx SMTP reply) as opposed to a
+// permanent one (a 5xx SMTP reply, a malformed message).
+func IsTemporary(err error) bool {
+	if err == nil {
+		return false
+	}
+	var t temporary
+	if errors.As(err, &t) {
+		return t.Temporary()
+	}
+	return false
+}
+
+// temporaryError wraps an error and marks it as transient.
+type temporaryError struct {
+	err error
+}
+
+func (e *temporaryError) Error() string   { return e.err.Error() }
+func (e *temporaryError) Unwrap() error   { return e.err }
+func (e *temporaryError) Temporary() bool { return true }
+
+// Temporary wraps err so that IsTemporary(err) reports true.
+func Temporary(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &temporaryError{err: err}
+}
+
+// sendResult is the "result" label recorded against metrics.SendTotal.
+func sendResult(err error) string {
+	if err == nil {
+		return "sent"
+	}
+	return "error"
+}
+
+// classifyReply wraps err with Temporary when code is a 4xx SMTP reply
+// (worth retrying/failing over), and returns it unwrapped (permanent)
+// for a 5xx reply or any other code.
+func classifyReply(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	if code >= 400 && code < 500 {
+		return Temporary(err)
+	}
+	return err
+}